@@ -8,6 +8,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/rsafonseca/helm-schema/pkg/schema"
 )
 
 func possibleLogLevels() []string {
@@ -54,6 +56,8 @@ func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Comma
 		BoolP("append-newline", "a", false, "append newline to generated jsonschema at the end of the file")
 	cmd.PersistentFlags().
 		BoolP("keep-full-comment", "s", false, "keep the whole leading comment (default: cut at empty line)")
+	cmd.PersistentFlags().
+		Bool("foot-comments", false, "add the comment following the value of a key to its description")
 	cmd.PersistentFlags().
 		BoolP("uncomment", "u", false, "consider yaml which is commented out")
 	cmd.PersistentFlags().
@@ -70,9 +74,85 @@ func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Comma
 	cmd.PersistentFlags().
 		StringP("output-file", "o", "values.schema.json", "jsonschema file path relative to each chart directory to which jsonschema will be written")
 	cmd.PersistentFlags().
-		StringSliceP("skip-auto-generation", "k", []string{}, "comma separated list of fields to skip from being created by default (possible: title, description, required, default, additionalProperties)")
+		String("flat-output-file", "", "additional jsonschema file path relative to each chart directory, which validates flat keys as used by helm's --set option (default: disabled)")
+	cmd.PersistentFlags().
+		StringSliceP("skip-auto-generation", "k", []string{}, "comma separated list of fields to skip from being created by default (possible: title, description, required, default, additionalProperties, global)")
+	cmd.PersistentFlags().
+		StringSlice("example-files", []string{}, "values files relative to each chart directory (e.g. of environments), whose values are added as examples of the matching keys")
+	cmd.PersistentFlags().
+		Bool("coerce-examples", false, "convert examples written as strings to the type of non-string values")
+	cmd.PersistentFlags().
+		StringSlice("custom-formats", []string{}, "custom string formats accepted by the format annotation, optionally with a pattern which is emitted with them (name=regex)")
+	cmd.PersistentFlags().
+		Bool("custom-annotations-nested", false, "emit the custom annotations nested in a single x-meta object instead of inlining them")
+	cmd.PersistentFlags().
+		Bool("custom-annotations-camel-case", false, "emit the keys of custom annotations in camelCase (e.g. x-foo-bar becomes x-fooBar)")
+	cmd.PersistentFlags().
+		String("null-handling", "", "type of keys with a null value (possible: strict, permissive, default: no type)")
+	cmd.PersistentFlags().
+		String("multi-document", "", "how to handle values files with multiple yaml documents (possible: first, any-of, default: error)")
+	cmd.PersistentFlags().
+		Bool("zero-defaults", false, "use the zero value of the annotated type as default of keys with a null value")
+	cmd.PersistentFlags().
+		Bool("sort-set-defaults", false, "sort the default of keys annotated with set: true")
+	cmd.PersistentFlags().
+		String("placeholder-handling", "", "how to treat values matching the placeholder pattern (possible: omit-default, pattern)")
+	cmd.PersistentFlags().
+		String("placeholder-pattern", schema.DefaultPlaceholderPattern, "regex matching placeholder values, like environment variables which are substituted later on")
+	cmd.PersistentFlags().
+		Bool("check-items", false, "warn if the values of a list don't match the type of its annotated items")
+	cmd.PersistentFlags().
+		Bool("strict-types", false, "warn about keys with an empty value and without an annotated type")
+	cmd.PersistentFlags().
+		Bool("timestamp-formats", false, "add format date or date-time to timestamp values")
+	cmd.PersistentFlags().
+		Bool("string-formats", false, "add format uri, email, ipv4 or date to string values which look like one of them")
+	cmd.PersistentFlags().
+		Int("max-enum-size", 0, "maximum number of enum members, larger enums are handled according to the enum overflow (0 means unlimited)")
+	cmd.PersistentFlags().
+		String("enum-overflow", "", "what to do with enums exceeding the max enum size (possible: drop, default: warn)")
+	cmd.PersistentFlags().
+		Bool("enum-titles", false, "emit enums with an x-enumDescriptions annotation as oneOf of consts titled by the descriptions")
+	cmd.PersistentFlags().
+		Bool("fail-on-warning", false, "fail if the generation or the lints of a schema produce warnings")
+	cmd.PersistentFlags().
+		Bool("helm-docs-title", false, "use the first sentence of helm-docs comments as title and the rest as description")
+	cmd.PersistentFlags().
+		Bool("kubernetes-names", false, "add the maxLength and pattern of kubernetes resource names to string values whose key matches the kubernetes name keys")
+	cmd.PersistentFlags().
+		String("kubernetes-name-keys", schema.DefaultKubernetesNameKeyPattern, "regex matching the keys of kubernetes resource names")
+	cmd.PersistentFlags().
+		Bool("infer", false, "enable all inference heuristics (humanized titles, formats, secrets, kubernetes names, required if non-empty, permissive null handling) with their default patterns")
+	cmd.PersistentFlags().
+		Bool("humanize-titles", false, "use the words of the key as title (e.g. \"Replica Count\" for replicaCount)")
+	cmd.PersistentFlags().
+		Bool("secrets", false, "make string values whose key matches the secret keys writeOnly and omit their default")
+	cmd.PersistentFlags().
+		String("secret-keys", schema.DefaultSecretKeyPattern, "regex matching the keys of sensitive values")
+	cmd.PersistentFlags().
+		Bool("required-if-non-empty", false, "only add keys with a non-empty value (not null, \"\", {} or []) to the required keys")
+	cmd.PersistentFlags().
+		Bool("one-of-overlaps", false, "warn about oneOf branches which don't differ in their type, const, enum, pattern or required keys")
+	cmd.PersistentFlags().
+		String("property-casing", "", "warn about keys which don't follow this casing (possible: camelCase, snake_case, kebab-case)")
+	cmd.PersistentFlags().
+		Bool("read-only-not-required", false, "never add keys annotated with readOnly: true to the required keys")
+	cmd.PersistentFlags().
+		Bool("replace-templates", false, "replace helm template expressions ({{ ... }}) in the values before parsing them")
+	cmd.PersistentFlags().
+		String("translations", "", "yaml file relative to each chart directory, which maps locales to the localized descriptions of the keys (e.g. de: {image.tag: ...})")
+	cmd.PersistentFlags().
+		String("locale", "", "locale of the translations to use for the descriptions")
+	cmd.PersistentFlags().
+		Bool("validate-defaults", false, "fail if a default or example isn't valid against the schema of its key")
+	cmd.PersistentFlags().
+		Bool("undefined-required", false, "warn about required keys which aren't defined in the properties of their parent")
+	cmd.PersistentFlags().
+		Bool("unevaluated-properties", false, "use unevaluatedProperties instead of additionalProperties for objects composed with allOf or $ref (requires draft 2019-09 or newer)")
 	cmd.PersistentFlags().
 		StringP("schema-id", "i", "undefined", "The schema id")
+	cmd.PersistentFlags().
+		String("schema-uri", schema.DefaultSchemaURI, "the $schema (dialect) of the generated schemas")
 	cmd.PersistentFlags().
 		StringP("schema-title", "t", "undefined", "The schema title")
 
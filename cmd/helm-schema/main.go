@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -38,7 +39,7 @@ func searchFiles(startPath, fileName string, queue chan<- string, errs chan<- er
 func exec(cmd *cobra.Command, _ []string) error {
 	configureLogging()
 
-	var skipAutoGeneration, valueFileNames []string
+	var skipAutoGeneration, valueFileNames, exampleFiles, customFormats []string
 
 	chartSearchRoot := viper.GetString("chart-search-root")
 	dryRun := viper.GetBool("dry-run")
@@ -48,16 +49,33 @@ func exec(cmd *cobra.Command, _ []string) error {
 	uncomment := viper.GetBool("uncomment")
 	outputUncommented := viper.GetBool("output-uncommented")
 	outFile := viper.GetString("output-file")
+	flatOutFile := viper.GetString("flat-output-file")
 	dontRemoveHelmDocsPrefix := viper.GetBool("dont-strip-helm-docs-prefix")
 	appendNewline := viper.GetBool("append-newline")
 	schemaId := viper.GetString("schema-id")
 	schemaTitle := viper.GetString("schema-title")
+	customAnnotationsOutput := schema.CustomAnnotationsOutput{
+		Nested:    viper.GetBool("custom-annotations-nested"),
+		CamelCase: viper.GetBool("custom-annotations-camel-case"),
+	}
 	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
 		return err
 	}
 	if err := viper.UnmarshalKey("skip-auto-generation", &skipAutoGeneration); err != nil {
 		return err
 	}
+	if err := viper.UnmarshalKey("example-files", &exampleFiles); err != nil {
+		return err
+	}
+	if err := viper.UnmarshalKey("custom-formats", &customFormats); err != nil {
+		return err
+	}
+	for _, customFormat := range customFormats {
+		name, pattern, _ := strings.Cut(customFormat, "=")
+		if err := schema.RegisterFormatPattern(name, pattern); err != nil {
+			return fmt.Errorf("invalid custom format %s: %w", customFormat, err)
+		}
+	}
 	workersCount := runtime.NumCPU() * 2
 
 	skipConfig, err := schema.NewSkipAutoGenerationConfig(skipAutoGeneration)
@@ -65,6 +83,87 @@ func exec(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	placeholderHandling, err := schema.NewPlaceholderHandling(viper.GetString("placeholder-handling"))
+	if err != nil {
+		return err
+	}
+	placeholderPattern, err := regexp.Compile(viper.GetString("placeholder-pattern"))
+	if err != nil {
+		return err
+	}
+	var propertyCasing schema.Casing
+	if name := viper.GetString("property-casing"); name != "" {
+		propertyCasing, err = schema.NewCasing(name)
+		if err != nil {
+			return err
+		}
+	}
+	multiDocumentHandling, err := schema.NewMultiDocumentHandling(viper.GetString("multi-document"))
+	if err != nil {
+		return err
+	}
+	enumOverflow, err := schema.NewEnumOverflow(viper.GetString("enum-overflow"))
+	if err != nil {
+		return err
+	}
+	if viper.GetString("translations") != "" && viper.GetString("locale") == "" {
+		return errors.New("the translations require a locale")
+	}
+	// --infer enables all heuristics, the single flags enable them one by one
+	var inference schema.InferenceOptions
+	inferAll := viper.GetBool("infer")
+	if inferAll {
+		inference = schema.DefaultInferenceOptions()
+	}
+	inference.HumanizeTitles = inference.HumanizeTitles || viper.GetBool("humanize-titles")
+	inference.TimestampFormats = inference.TimestampFormats || viper.GetBool("timestamp-formats")
+	inference.StringFormats = inference.StringFormats || viper.GetBool("string-formats")
+	inference.RequiredIfNonEmpty = inference.RequiredIfNonEmpty || viper.GetBool("required-if-non-empty")
+	if inferAll || viper.GetBool("kubernetes-names") {
+		inference.KubernetesNameKeys, err = regexp.Compile(viper.GetString("kubernetes-name-keys"))
+		if err != nil {
+			return err
+		}
+	}
+	if inferAll || viper.GetBool("secrets") {
+		inference.SecretKeys, err = regexp.Compile(viper.GetString("secret-keys"))
+		if err != nil {
+			return err
+		}
+	}
+	if name := viper.GetString("null-handling"); name != "" || !inferAll {
+		inference.NullHandling, err = schema.NewNullHandling(name)
+		if err != nil {
+			return err
+		}
+	}
+	generateOptions := &schema.GenerateOptions{
+		PlaceholderHandling:   placeholderHandling,
+		PlaceholderPattern:    placeholderPattern,
+		Inference:             inference,
+		CheckItemsConsistency: viper.GetBool("check-items"),
+		BaseURI:               schemaId,
+		UnevaluatedProperties: viper.GetBool("unevaluated-properties"),
+		ReplaceTemplates:      viper.GetBool("replace-templates"),
+		HelmDocsTitle:         viper.GetBool("helm-docs-title"),
+		ReadOnlyNotRequired:   viper.GetBool("read-only-not-required"),
+		MaxEnumSize:           viper.GetInt("max-enum-size"),
+		EnumOverflow:          enumOverflow,
+		EnumTitles:            viper.GetBool("enum-titles"),
+		StrictTypes:           viper.GetBool("strict-types"),
+		MultiDocumentHandling: multiDocumentHandling,
+		CoerceExamples:        viper.GetBool("coerce-examples"),
+		ExampleFiles:          exampleFiles,
+		FootComments:          viper.GetBool("foot-comments"),
+		ZeroDefaults:          viper.GetBool("zero-defaults"),
+		SortSetDefaults:       viper.GetBool("sort-set-defaults"),
+		ValidateDefaults:      viper.GetBool("validate-defaults"),
+		TranslationsFile:      viper.GetString("translations"),
+		Locale:                viper.GetString("locale"),
+		SchemaURI:             viper.GetString("schema-uri"),
+		FailOnWarning:         viper.GetBool("fail-on-warning"),
+	}
+
 	// 1. Start a producer that searches Chart.yaml and values.yaml files
 	queue := make(chan string)
 	resultsChan := make(chan schema.Result)
@@ -97,6 +196,7 @@ func exec(cmd *cobra.Command, _ []string) error {
 				schemaTitle,
 				valueFileNames,
 				skipConfig,
+				generateOptions,
 				outFile,
 				queue,
 				resultsChan,
@@ -131,30 +231,22 @@ loop:
 		}
 	}
 
-	conditionsToPatch := make(map[string][]string)
-	// Sort results if dependencies should be processed
-	// Need to resolve the dependencies from deepest level to highest
+	foundErrors := false
 
-	if !noDeps {
-		// Iterate over deps to find conditions we need to patch (dependencies that have a condition)
-		for _, result := range results {
-			if len(result.Errors) > 0 {
-				continue
+	// report the errors and lints of the results
+	for _, result := range results {
+		if len(result.Errors) == 0 {
+			log.Debugf("Processing result for chart: %s (%s)", result.Chart.Name, result.ChartPath)
+			warnings := lint(result, propertyCasing)
+			for _, warning := range warnings {
+				log.Warn(warning)
 			}
-			for _, dep := range result.Chart.Dependencies {
-				if dep.Condition != "" {
-					conditionKeys := strings.Split(dep.Condition, ".")
-					conditionsToPatch[conditionKeys[0]] = conditionKeys[1:]
-				}
+			// like the warnings of the generation, the lints fail the chart
+			if generateOptions.FailOnWarning && len(warnings) > 0 {
+				result.Errors = append(result.Errors, fmt.Errorf("the lints produced %d warning(s):\n%s", len(warnings), strings.Join(warnings, "\n")))
 			}
 		}
-	}
-
-	chartNameToResult := make(map[string]*schema.Result)
-	foundErrors := false
 
-	// process results
-	for _, result := range results {
 		// Error handling
 		if len(result.Errors) > 0 {
 			foundErrors = true
@@ -171,76 +263,25 @@ loop:
 			for _, err := range result.Errors {
 				log.Error(err)
 			}
-			continue
 		}
+	}
 
-		log.Debugf("Processing result for chart: %s (%s)", result.Chart.Name, result.ChartPath)
-		if !noDeps {
-			// Patch condition into schema if needed
-			if patch, ok := conditionsToPatch[result.Chart.Name]; ok {
-				schemaToPatch := &result.Schema
-				lastIndex := len(patch) - 1
-				for i, key := range patch {
-					if alreadyPresentSchema, ok := schemaToPatch.Properties[key]; !ok {
-						log.Debugf(
-							"Patching conditional field \"%s\" into schema of chart %s",
-							key,
-							result.Chart.Name,
-						)
-						if i == lastIndex {
-							schemaToPatch.Properties[key] = &schema.Schema{
-								Type:        []string{"boolean"},
-								Title:       key,
-								Description: "Conditional property used in parent chart",
-							}
-						} else {
-							schemaToPatch.Properties[key] = &schema.Schema{Type: []string{"object"}, Title: key}
-							schemaToPatch = schemaToPatch.Properties[key]
-						}
-					} else {
-						schemaToPatch = alreadyPresentSchema
-					}
-				}
-			}
+	// Need to resolve the dependencies from deepest level to highest
+	if !noDeps {
+		schema.MergeDependencies(results)
+	}
 
-			for _, dep := range result.Chart.Dependencies {
-				if dep.Name != "" {
-					if dependencyResult, ok := chartNameToResult[dep.Name]; ok {
-						log.Debugf(
-							"Found chart of dependency %s (%s)",
-							dependencyResult.Chart.Name,
-							dependencyResult.ChartPath,
-						)
-						depSchema := schema.Schema{
-							Type:        []string{"object"},
-							Title:       dep.Name,
-							Description: dependencyResult.Chart.Description,
-							Properties:  dependencyResult.Schema.Properties,
-						}
-						// you don't NEED to overwrite the values
-						// so every required check will be disabled
-						depSchema.DisableRequiredProperties()
-
-						if dep.Alias != "" {
-							result.Schema.Properties[dep.Alias] = &depSchema
-						} else {
-							result.Schema.Properties[dep.Name] = &depSchema
-						}
-
-					} else {
-						log.Warnf("Dependency (%s->%s) specified but no schema found. If you want to create jsonschemas for external dependencies, you need to run helm dependency build & untar the charts.", result.Chart.Name, dep.Name)
-					}
-				} else {
-					log.Warnf("Dependency without name found (checkout %s).", result.ChartPath)
-				}
-			}
-			chartNameToResult[result.Chart.Name] = result
+	// process results
+	for _, result := range results {
+		if len(result.Errors) > 0 {
+			continue
 		}
 
 		// Print to stdout or write to file
-		jsonStr, err := result.Schema.ToJson()
+		jsonStr, err := result.Schema.ToJsonWithCustomAnnotations(customAnnotationsOutput)
 		if err != nil {
-			log.Error(err)
+			log.Errorf("%s: %v", result.ChartPath, err)
+			foundErrors = true
 			continue
 		}
 
@@ -262,6 +303,28 @@ loop:
 				continue
 			}
 		}
+
+		if flatOutFile != "" {
+			flatJsonStr, err := result.Schema.ToFlatSchema().ToJsonWithCustomAnnotations(customAnnotationsOutput)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+
+			if dryRun {
+				log.Infof("Printing flat jsonschema for %s chart (%s)", result.Chart.Name, result.ChartPath)
+				fmt.Printf("%s\n", flatJsonStr)
+			} else {
+				if appendNewline {
+					flatJsonStr = append(flatJsonStr, '\n')
+				}
+				chartBasePath := filepath.Dir(result.ChartPath)
+				if err := os.WriteFile(filepath.Join(chartBasePath, flatOutFile), flatJsonStr, 0644); err != nil {
+					log.Error(err)
+					foundErrors = true
+				}
+			}
+		}
 	}
 	if foundErrors {
 		return errors.New("some errors were found")
@@ -269,6 +332,37 @@ loop:
 	return nil
 }
 
+// lint returns the warnings of the lints for the schema of the result
+func lint(result *schema.Result, propertyCasing schema.Casing) []string {
+	var warnings []string
+	if propertyCasing != "" {
+		for _, path := range result.Schema.CasingViolations(propertyCasing) {
+			warnings = append(warnings, fmt.Sprintf("The key %s of chart %s isn't %s", path, result.Chart.Name, propertyCasing))
+		}
+	}
+	for _, path := range result.Schema.PatternPropertyConflicts() {
+		warnings = append(warnings, fmt.Sprintf("The type of key %s of chart %s contradicts a matching patternProperties schema", path, result.Chart.Name))
+	}
+	for _, path := range result.Schema.UndefinedDependentRequiredProperties() {
+		warnings = append(warnings, fmt.Sprintf("The key %s used in dependentRequired of chart %s isn't defined in the properties", path, result.Chart.Name))
+	}
+	if viper.GetBool("one-of-overlaps") {
+		for _, overlap := range result.Schema.OneOfOverlaps() {
+			key := overlap.Path
+			if key == "" {
+				key = "(root)"
+			}
+			warnings = append(warnings, fmt.Sprintf("The oneOf branches %d and %d of key %s of chart %s overlap, maybe use anyOf", overlap.First, overlap.Second, key, result.Chart.Name))
+		}
+	}
+	if viper.GetBool("undefined-required") {
+		for _, path := range result.Schema.UndefinedRequiredProperties() {
+			warnings = append(warnings, fmt.Sprintf("The required key %s of chart %s isn't defined in the properties", path, result.Chart.Name))
+		}
+	}
+	return warnings
+}
+
 func main() {
 	command, err := newCommand(exec)
 	if err != nil {
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+
+	"github.com/rsafonseca/helm-schema/pkg/schema"
+)
+
+func TestGenerateAndCheckMatchesOutput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml": `apiVersion: v2
+name: app
+version: 0.1.0
+dependencies:
+  - name: sub
+    version: 0.1.0
+    condition: sub.enabled
+`,
+		"values.yaml": `
+# @schema
+# $ref: port.json
+# @schema
+port: 80
+sub:
+  replicas: 1
+`,
+		"port.json":              `{"type": "integer", "minimum": 1}`,
+		"charts/sub/Chart.yaml":  "apiVersion: v2\nname: sub\nversion: 0.1.0\n",
+		"charts/sub/values.yaml": "replicas: 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	command, err := newCommand(exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command.SetArgs([]string{"--chart-search-root", dir, "--schema-title", "App", "--schema-id", "https://example.org/app.json"})
+	if err := command.Execute(); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	// the schema written by the CLI is up to date
+	opts := schema.GenerateOptions{Title: "App", BaseURI: "https://example.org/app.json"}
+	changed, diff, err := schema.GenerateAndCheck(filepath.Join(dir, "values.yaml"), filepath.Join(dir, "values.schema.json"), opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, diff, []schema.Change{})
+	assert.Equal(t, changed, false)
+}
+
+func TestFailOnLintWarning(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nname: app\nversion: 0.1.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "values.yaml"), []byte("replica_count: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, failOnWarning := range []bool{false, true} {
+		command, err := newCommand(exec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"--chart-search-root", dir, "--property-casing", "camelCase"}
+		if failOnWarning {
+			args = append(args, "--fail-on-warning")
+		}
+		command.SetArgs(args)
+		err = command.Execute()
+		assert.Equal(t, err != nil, failOnWarning)
+	}
+}
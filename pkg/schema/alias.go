@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const mergeTag = "!!merge"
+
+// maxResolvedNodes limits the number of nodes the aliases of a document can expand to, so nested aliases
+// (like in the "billion laughs" attack) can't exhaust the memory
+const maxResolvedNodes = 1000000
+
+// aliasResolver replaces the aliases of a yaml document by the nodes of their anchors
+type aliasResolver struct {
+	// anchorComments holds the head comment of the key of each anchored value
+	anchorComments map[*yaml.Node]string
+	// resolvedNodes counts the nodes of the resolved document
+	resolvedNodes int
+}
+
+// resolveAliases returns a copy of the node, in which aliases are replaced by copies of their anchored nodes
+// and merge keys (<<) by the keys of the merged maps. Keys whose value is an alias and which aren't commented
+// get the comment of the key of the anchor, so its annotations apply to them as well. An error is returned if
+// the resolved document would have more than maxResolvedNodes nodes.
+func resolveAliases(node *yaml.Node) (*yaml.Node, error) {
+	r := &aliasResolver{anchorComments: make(map[*yaml.Node]string)}
+	r.collectAnchorComments(node)
+	return r.resolve(node)
+}
+
+func (r *aliasResolver) collectAnchorComments(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := node.Content[i+1]; value.Anchor != "" {
+				r.anchorComments[value] = node.Content[i].HeadComment
+			}
+		}
+	}
+	for _, child := range node.Content {
+		r.collectAnchorComments(child)
+	}
+}
+
+func (r *aliasResolver) resolve(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind == yaml.AliasNode {
+		return r.resolve(node.Alias)
+	}
+	r.resolvedNodes++
+	if r.resolvedNodes > maxResolvedNodes {
+		return nil, fmt.Errorf("the aliases of the document expand to more than %d nodes", maxResolvedNodes)
+	}
+
+	resolved := *node
+	resolved.Content = nil
+	if node.Kind != yaml.MappingNode {
+		for _, child := range node.Content {
+			resolvedChild, err := r.resolve(child)
+			if err != nil {
+				return nil, err
+			}
+			resolved.Content = append(resolved.Content, resolvedChild)
+		}
+		return &resolved, nil
+	}
+
+	// keys of the map itself take precedence over merged keys, but keep their comment if they have none
+	explicitKeys := make(map[string]bool)
+	mergedComments := make(map[string]string)
+	mergedMaps := make(map[int][]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() != mergeTag {
+			explicitKeys[key.Value] = true
+			continue
+		}
+		maps, err := r.mergedMaps(value)
+		if err != nil {
+			return nil, err
+		}
+		mergedMaps[i] = maps
+		for _, merged := range mergedMaps[i] {
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				if _, ok := mergedComments[merged.Content[j].Value]; !ok {
+					mergedComments[merged.Content[j].Value] = merged.Content[j].HeadComment
+				}
+			}
+		}
+	}
+
+	mergedKeys := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() == mergeTag {
+			// of multiple merged maps, the earlier ones take precedence
+			for _, merged := range mergedMaps[i] {
+				for j := 0; j+1 < len(merged.Content); j += 2 {
+					name := merged.Content[j].Value
+					if explicitKeys[name] || mergedKeys[name] {
+						continue
+					}
+					mergedKeys[name] = true
+					resolved.Content = append(resolved.Content, merged.Content[j], merged.Content[j+1])
+				}
+			}
+			continue
+		}
+
+		resolvedKey := *key
+		if resolvedKey.HeadComment == "" {
+			if value.Kind == yaml.AliasNode {
+				resolvedKey.HeadComment = r.anchorComments[value.Alias]
+			} else {
+				resolvedKey.HeadComment = mergedComments[key.Value]
+			}
+		}
+		resolvedValue, err := r.resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Content = append(resolved.Content, &resolvedKey, resolvedValue)
+	}
+	return &resolved, nil
+}
+
+// mergedMaps returns the resolved maps of the value of a merge key, which is a map or a list of maps
+func (r *aliasResolver) mergedMaps(value *yaml.Node) ([]*yaml.Node, error) {
+	resolved, err := r.resolve(value)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Kind == yaml.SequenceNode {
+		return resolved.Content, nil
+	}
+	return []*yaml.Node{resolved}, nil
+}
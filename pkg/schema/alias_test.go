@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveAliases(t *testing.T) {
+	data := `
+defaults: &defaults
+  # @schema
+  # minimum: 1
+  # @schema
+  replicas: 1
+  image: foo
+prod:
+  <<: *defaults
+  replicas: 3
+staging: *defaults
+# @schema
+# enum: [debug, info]
+# @schema
+level: &level info
+otherLevel: *level
+sidecar:
+  <<: [{port: 80}, {port: 8080, host: localhost}]
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+	s, err := Generate(GenerateOptions{}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	// merged keys are part of the map, explicit keys override them but keep their annotation
+	prod := s.Properties["prod"]
+	assert.Equal(t, prod.Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, prod.Properties["image"].Default, "foo")
+	assert.Equal(t, prod.Properties["replicas"].Default, 3)
+	assert.Equal(t, *prod.Properties["replicas"].Minimum, 1)
+
+	// aliases are replaced by their anchored values
+	staging := s.Properties["staging"]
+	assert.Equal(t, staging.Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, staging.Properties["replicas"].Default, 1)
+	assert.Equal(t, *staging.Properties["replicas"].Minimum, 1)
+
+	// the annotation of the anchor applies to uncommented aliases
+	assert.Equal(t, s.Properties["otherLevel"].Enum, []interface{}{"debug", "info"})
+	assert.Equal(t, s.Properties["otherLevel"].Default, "info")
+
+	// of multiple merged maps, the first one takes precedence
+	sidecar := s.Properties["sidecar"]
+	assert.Equal(t, sidecar.Properties["port"].Default, 80)
+	assert.Equal(t, sidecar.Properties["host"].Default, "localhost")
+
+	// the given node isn't modified
+	assert.Equal(t, node.Content[0].Content[5].Kind, yaml.AliasNode)
+}
+
+func TestResolveAliasesLimit(t *testing.T) {
+	// each level references the previous one ten times, which expands to 10^9 values
+	data := "a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n"
+	for i, name := range []string{"b", "c", "d", "e", "f", "g", "h", "i"} {
+		previous := string(rune('a' + i))
+		data += fmt.Sprintf("%s: &%s [", name, name) + strings.Repeat("*"+previous+", ", 9) + "*" + previous + "]\n"
+	}
+	_, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+	if err == nil || !strings.Contains(err.Error(), "expand to more than") {
+		t.Errorf("Expected an error for the expansion of the aliases, but got: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// annotationSkipKeywords aren't written into annotations, because they're generated from the values anyway
+var annotationSkipKeywords = []string{
+	"title", "description", "default", "properties", "required", "additionalProperties", "$schema",
+}
+
+// AnnotateValues adds @schema annotations with the constraints of the existing schema to the keys of the
+// values file, so the annotations can be maintained instead of the schema. Keys which are already annotated
+// are kept as they are, only the lines of the annotations are inserted into the file.
+func AnnotateValues(valuesPath, schemaPath string) error {
+	schemaContent, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return err
+	}
+	var existing Schema
+	if err := json.Unmarshal(schemaContent, &existing); err != nil {
+		return fmt.Errorf("%s: %w", schemaPath, err)
+	}
+
+	fileInfo, err := os.Stat(valuesPath)
+	if err != nil {
+		return err
+	}
+	valuesContent, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return err
+	}
+	var values yaml.Node
+	if err := yaml.Unmarshal(valuesContent, &values); err != nil {
+		return fmt.Errorf("%s: %w", valuesPath, err)
+	}
+	if len(values.Content) == 0 {
+		return nil
+	}
+
+	annotations := make(map[int]string)
+	if err := annotateNode(values.Content[0], existing.Properties, annotations); err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	return os.WriteFile(valuesPath, insertAnnotations(valuesContent, annotations), fileInfo.Mode().Perm())
+}
+
+// annotateNode collects the annotations of the keys of the mapping node which have a property, keyed by the
+// line of the key and indented like it. Keys of flow mappings are skipped, they can't have comments above them.
+func annotateNode(node *yaml.Node, properties map[string]*Schema, annotations map[int]string) error {
+	if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+		property, ok := properties[keyNode.Value]
+		if !ok {
+			continue
+		}
+
+		if !strings.Contains(keyNode.HeadComment, "@schema") {
+			annotation, err := schemaAnnotation(property, valueNode)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", keyNode.Value, err)
+			}
+			if annotation != "" {
+				indent := strings.Repeat(" ", keyNode.Column-1)
+				annotations[keyNode.Line] = indent + strings.ReplaceAll(annotation, "\n", "\n"+indent)
+			}
+		}
+
+		if err := annotateNode(valueNode, property.Properties, annotations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertAnnotations inserts the annotations above their lines, the other lines are kept byte for byte
+func insertAnnotations(content []byte, annotations map[int]string) []byte {
+	newline := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	var output bytes.Buffer
+	for i, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if annotation, ok := annotations[i+1]; ok {
+			output.WriteString(strings.ReplaceAll(annotation, "\n", newline) + newline)
+		}
+		output.Write(line)
+	}
+	return output.Bytes()
+}
+
+// schemaAnnotation returns the @schema block with the constraints of the property. Keywords which are
+// generated from the value are omitted, an empty string is returned if nothing is left.
+func schemaAnnotation(property *Schema, valueNode *yaml.Node) (string, error) {
+	value, err := toJSONValue(property)
+	if err != nil {
+		return "", err
+	}
+	keywords, ok := value.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	for _, keyword := range annotationSkipKeywords {
+		delete(keywords, keyword)
+	}
+	if valueType, err := TypeFromTag(valueNode.Tag); err == nil && len(valueType) == 1 && keywords["type"] == valueType[0] {
+		delete(keywords, "type")
+	}
+	if len(keywords) == 0 {
+		return "", nil
+	}
+
+	annotation, err := yaml.Marshal(keywords)
+	if err != nil {
+		return "", err
+	}
+	lines := []string{"# @schema"}
+	for _, line := range strings.Split(strings.TrimSuffix(string(annotation), "\n"), "\n") {
+		lines = append(lines, "# "+line)
+	}
+	lines = append(lines, "# @schema")
+	return strings.Join(lines, "\n"), nil
+}
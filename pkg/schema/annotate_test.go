@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestAnnotateValues(t *testing.T) {
+	dir := t.TempDir()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	schemaPath := filepath.Join(dir, "values.schema.json")
+	values := `# the number of replicas
+replicas: 1
+service:
+  port: 80
+  name: web
+# @schema
+# type: string
+# @schema
+image: nginx
+`
+	existing := `{
+  "type": "object",
+  "required": ["replicas"],
+  "properties": {
+    "replicas": {"type": "integer", "title": "replicas", "minimum": 1, "maximum": 10},
+    "service": {
+      "type": "object",
+      "properties": {
+        "port": {"type": "integer", "default": 80, "exclusiveMaximum": 65536},
+        "name": {"type": "string", "title": "name"}
+      }
+    },
+    "image": {"type": "string", "pattern": "^nginx"}
+  }
+}`
+	if err := os.WriteFile(valuesPath, []byte(values), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemaPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AnnotateValues(valuesPath, schemaPath); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	annotated, err := os.ReadFile(valuesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(annotated), `# the number of replicas
+# @schema
+# maximum: 10
+# minimum: 1
+# @schema
+replicas: 1
+service:
+  # @schema
+  # exclusiveMaximum: 65536
+  # @schema
+  port: 80
+  name: web
+# @schema
+# type: string
+# @schema
+image: nginx
+`)
+	// the annotations result in the same constraints
+	content := strings.NewReader(string(annotated))
+	generated, err := GenerateFromReader(content, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, *generated.Properties["replicas"].Maximum, 10)
+	assert.Equal(t, *generated.Properties["service"].Properties["port"].ExclusiveMaximum, 65536)
+}
+
+func TestAnnotateValuesKeepsLines(t *testing.T) {
+	dir := t.TempDir()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	schemaPath := filepath.Join(dir, "values.schema.json")
+	values := `---
+# yaml-language-server: $schema=values.schema.json
+
+replicas:   1    # trailing comment
+service:
+    ports: [80, 443]
+    labels: {app: "web"}
+    name: 'web'
+`
+	existing := `{
+  "type": "object",
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "service": {
+      "type": "object",
+      "properties": {
+        "ports": {"type": "array", "maxItems": 2},
+        "labels": {"type": "object", "properties": {"app": {"type": "string", "minLength": 1}}},
+        "name": {"type": "string", "maxLength": 63}
+      }
+    }
+  }
+}`
+	if err := os.WriteFile(valuesPath, []byte(values), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemaPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AnnotateValues(valuesPath, schemaPath); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	annotated, err := os.ReadFile(valuesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the annotations are inserted, the keys of flow mappings can't be annotated
+	assert.Equal(t, string(annotated), `---
+# yaml-language-server: $schema=values.schema.json
+
+# @schema
+# minimum: 1
+# @schema
+replicas:   1    # trailing comment
+service:
+    # @schema
+    # maxItems: 2
+    # @schema
+    ports: [80, 443]
+    labels: {app: "web"}
+    # @schema
+    # maxLength: 63
+    # @schema
+    name: 'web'
+`)
+}
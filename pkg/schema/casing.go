@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Casing is a naming convention for property names
+type Casing string
+
+const (
+	CasingCamel Casing = "camelCase"
+	CasingSnake Casing = "snake_case"
+	CasingKebab Casing = "kebab-case"
+)
+
+var casingPatterns = map[Casing]*regexp.Regexp{
+	CasingCamel: regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	CasingSnake: regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	CasingKebab: regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+}
+
+// NewCasing parses the given casing name
+func NewCasing(name string) (Casing, error) {
+	casing := Casing(name)
+	if _, ok := casingPatterns[casing]; !ok {
+		return "", fmt.Errorf("unsupported casing '%s'", name)
+	}
+	return casing, nil
+}
+
+// CasingViolations returns the dotted paths of all properties whose name doesn't follow the given casing.
+// The built-in global property is ignored.
+func (s *Schema) CasingViolations(casing Casing) []string {
+	pattern := casingPatterns[casing]
+	violations := []string{}
+	s.WalkProperties(func(path string, _ *Schema) {
+		if path == "global" || strings.HasPrefix(path, "global.") {
+			return
+		}
+		name := path[strings.LastIndex(path, ".")+1:]
+		if !pattern.MatchString(name) {
+			violations = append(violations, path)
+		}
+	})
+	return violations
+}
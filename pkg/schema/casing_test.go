@@ -0,0 +1,35 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCasingViolations(t *testing.T) {
+	data := `
+replicaCount: 1
+image_tag: latest
+service:
+  port-name: http
+  targetPort: 80
+  Type: ClusterIP
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	tests := []struct {
+		casing   Casing
+		expected []string
+	}{
+		{casing: CasingCamel, expected: []string{"image_tag", "service.Type", "service.port-name"}},
+		{casing: CasingSnake, expected: []string{"replicaCount", "service.Type", "service.port-name", "service.targetPort"}},
+		{casing: CasingKebab, expected: []string{"image_tag", "replicaCount", "service.Type", "service.targetPort"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, s.CasingViolations(test.casing), test.expected)
+	}
+
+	if _, err := NewCasing("PascalCase"); err == nil {
+		t.Errorf("Expected an error for an unsupported casing")
+	}
+}
@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// DefaultViolations returns the json pointers of the defaults and examples, which aren't valid against the
+// schema they're part of, together with the reason (e.g. "#/properties/port/default: must be >= 10 but found 5")
+func (s *Schema) DefaultViolations() ([]string, error) {
+	data, err := s.ToJson()
+	if err != nil {
+		return nil, err
+	}
+	var root interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource("schema.json", bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	violations := []string{}
+	var check func(pointer string, value interface{}) error
+	check = func(pointer string, value interface{}) error {
+		schema, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		var instances []string
+		if _, ok := schema["default"]; ok {
+			instances = append(instances, "/default")
+		}
+		if examples, ok := schema["examples"].([]interface{}); ok {
+			for i := range examples {
+				instances = append(instances, "/examples/"+strconv.Itoa(i))
+			}
+		}
+		if len(instances) > 0 {
+			compiled, err := compiler.Compile((&url.URL{Path: "schema.json", Fragment: pointer}).String())
+			if err != nil {
+				return err
+			}
+			for _, instance := range instances {
+				if err := compiled.Validate(instanceAt(schema, instance)); err != nil {
+					violations = append(violations, fmt.Sprintf("#%s%s: %s", pointer, instance, violationMessage(err)))
+				}
+			}
+		}
+
+		for _, keyword := range schemaKeywords {
+			switch subSchema := schema[keyword].(type) {
+			case map[string]interface{}:
+				if err := check(pointer+"/"+keyword, subSchema); err != nil {
+					return err
+				}
+			case []interface{}:
+				for i, item := range subSchema {
+					if err := check(pointer+"/"+keyword+"/"+strconv.Itoa(i), item); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		for _, keyword := range schemaMapKeywords {
+			subSchemas, _ := schema[keyword].(map[string]interface{})
+			names := make([]string, 0, len(subSchemas))
+			for name := range subSchemas {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				if err := check(pointer+"/"+keyword+"/"+pointerEscaper.Replace(name), subSchemas[name]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := check("", root); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+// instanceAt returns the default or an example of the schema
+func instanceAt(schema map[string]interface{}, instance string) interface{} {
+	if instance == "/default" {
+		return schema["default"]
+	}
+	index, _ := strconv.Atoi(instance[len("/examples/"):])
+	return schema["examples"].([]interface{})[index]
+}
+
+// violationMessage returns the reasons of the validation error without the locations
+func violationMessage(err error) string {
+	validationError, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err.Error()
+	}
+	for len(validationError.Causes) == 1 {
+		validationError = validationError.Causes[0]
+	}
+	return validationError.Message
+}
@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MergeDependencies adds the schemas of the dependencies to the schemas of their parent charts and patches the
+// conditions of the dependencies (e.g. postgresql.enabled) into the schemas of the dependency charts. The results
+// must be sorted by TopoSort, so the dependencies are merged before their parents. Results with errors are skipped.
+func MergeDependencies(results []*Result) {
+	// Iterate over deps to find conditions we need to patch (dependencies that have a condition)
+	conditionsToPatch := make(map[string][]string)
+	for _, result := range results {
+		if len(result.Errors) > 0 {
+			continue
+		}
+		for _, dep := range result.Chart.Dependencies {
+			if dep.Condition != "" {
+				conditionKeys := strings.Split(dep.Condition, ".")
+				conditionsToPatch[conditionKeys[0]] = conditionKeys[1:]
+			}
+		}
+	}
+
+	chartNameToResult := make(map[string]*Result)
+	for _, result := range results {
+		if len(result.Errors) > 0 {
+			continue
+		}
+
+		// Patch condition into schema if needed
+		if patch, ok := conditionsToPatch[result.Chart.Name]; ok {
+			// multi document values have a schema per document
+			for _, documentSchema := range result.Schema.Documents() {
+				schemaToPatch := documentSchema
+				lastIndex := len(patch) - 1
+				for i, key := range patch {
+					if alreadyPresentSchema, ok := schemaToPatch.Properties[key]; !ok {
+						log.Debugf(
+							"Patching conditional field \"%s\" into schema of chart %s",
+							key,
+							result.Chart.Name,
+						)
+						if i == lastIndex {
+							schemaToPatch.Properties[key] = &Schema{
+								Type:        []string{"boolean"},
+								Title:       key,
+								Description: "Conditional property used in parent chart",
+							}
+						} else {
+							schemaToPatch.Properties[key] = &Schema{Type: []string{"object"}, Title: key}
+							schemaToPatch = schemaToPatch.Properties[key]
+						}
+					} else {
+						schemaToPatch = alreadyPresentSchema
+					}
+				}
+			}
+		}
+
+		for _, dep := range result.Chart.Dependencies {
+			if dep.Name != "" {
+				if dependencyResult, ok := chartNameToResult[dep.Name]; ok {
+					log.Debugf(
+						"Found chart of dependency %s (%s)",
+						dependencyResult.Chart.Name,
+						dependencyResult.ChartPath,
+					)
+					depSchema := Schema{
+						Type:        []string{"object"},
+						Title:       dep.Name,
+						Description: dependencyResult.Chart.Description,
+						Properties:  dependencyResult.Schema.Properties,
+					}
+					if documents := dependencyResult.Schema.Documents(); len(documents) > 1 {
+						depSchema.AnyOf = documents
+					}
+					// you don't NEED to overwrite the values
+					// so every required check will be disabled
+					depSchema.DisableRequiredProperties()
+
+					for _, documentSchema := range result.Schema.Documents() {
+						if dep.Alias != "" {
+							documentSchema.Properties[dep.Alias] = &depSchema
+						} else {
+							documentSchema.Properties[dep.Name] = &depSchema
+						}
+					}
+
+				} else {
+					log.Warnf("Dependency (%s->%s) specified but no schema found. If you want to create jsonschemas for external dependencies, you need to run helm dependency build & untar the charts.", result.Chart.Name, dep.Name)
+				}
+			} else {
+				log.Warnf("Dependency without name found (checkout %s).", result.ChartPath)
+			}
+		}
+		chartNameToResult[result.Chart.Name] = result
+	}
+}
@@ -0,0 +1,267 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change describes a single difference between two schemas. Path is a json pointer to the changed
+// value, Old is nil for added values and New is nil for removed values.
+type Change struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("added %s: %v", c.Path, c.New)
+	case c.New == nil:
+		return fmt.Sprintf("removed %s: %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("changed %s: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// Equal reports whether both schemas result in the same json
+func Equal(a, b *Schema) (bool, error) {
+	changes, err := Diff(a, b)
+	if err != nil {
+		return false, err
+	}
+	return len(changes) == 0, nil
+}
+
+// Diff returns the changes needed to get from the old to the new schema, compared by their json
+// representation
+func Diff(old, new *Schema) ([]Change, error) {
+	oldValue, err := toJSONValue(old)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := toJSONValue(new)
+	if err != nil {
+		return nil, err
+	}
+	return diffJSON("", oldValue, newValue), nil
+}
+
+// GenerateAndCheck generates the schema for the given values file and compares it with the existing
+// schema file. It reports whether the existing schema would change on regeneration. If the values file belongs
+// to a chart, the schema is generated like the CLI does, so the dependencies found below the chart directory are
+// merged into it.
+func GenerateAndCheck(valuesPath, existingSchemaPath string, opts GenerateOptions) (bool, []Change, error) {
+	opts.ValuesPath = valuesPath
+	generated, err := generateChartSchema(valuesPath, opts)
+	if err != nil {
+		return false, nil, err
+	}
+	newValue, err := toJSONValue(generated)
+	if err != nil {
+		return false, nil, err
+	}
+
+	existing, err := os.ReadFile(existingSchemaPath)
+	if err != nil {
+		return false, nil, err
+	}
+	var oldValue interface{}
+	if err := json.Unmarshal(existing, &oldValue); err != nil {
+		return false, nil, fmt.Errorf("%s: %w", existingSchemaPath, err)
+	}
+
+	changes := diffJSON("", oldValue, newValue)
+	return len(changes) > 0, changes, nil
+}
+
+// generateChartSchema generates the schema of the values file. For the values of a chart, the Worker generates the
+// schemas of the chart and the charts below it, whose schemas are merged into the one of the chart.
+func generateChartSchema(valuesPath string, opts GenerateOptions) (*Schema, error) {
+	chartDir := filepath.Dir(valuesPath)
+	chartPath := filepath.Join(chartDir, "Chart.yaml")
+	if _, err := os.Stat(chartPath); errors.Is(err, os.ErrNotExist) {
+		valuesFile, err := os.Open(valuesPath)
+		if err != nil {
+			return nil, err
+		}
+		defer valuesFile.Close()
+		return GenerateFromReader(valuesFile, opts)
+	}
+
+	var chartPaths []string
+	err := filepath.WalkDir(chartDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && entry.Name() == "Chart.yaml" {
+			chartPaths = append(chartPaths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queue := make(chan string, len(chartPaths))
+	for _, path := range chartPaths {
+		queue <- path
+	}
+	close(queue)
+	resultsChan := make(chan Result, len(chartPaths))
+	Worker(
+		false, false, false, false,
+		opts.KeepFullComment,
+		opts.DontRemoveHelmDocsPrefix,
+		opts.BaseURI,
+		opts.Title,
+		[]string{filepath.Base(valuesPath)},
+		opts.SkipAutoGeneration,
+		&opts,
+		"",
+		queue,
+		resultsChan,
+	)
+	close(resultsChan)
+
+	// like the CLI, dependencies which can't be generated are left out
+	results := []*Result{}
+	for result := range resultsChan {
+		if len(result.Errors) > 0 {
+			if result.ChartPath == chartPath {
+				return nil, errors.Join(result.Errors...)
+			}
+			continue
+		}
+		results = append(results, &result)
+	}
+	// missing dependencies are reported as CircularError, the results are still complete
+	sorted, err := TopoSort(results)
+	if _, ok := err.(*CircularError); err != nil && !ok {
+		return nil, err
+	}
+	MergeDependencies(sorted)
+
+	for _, result := range sorted {
+		if result.ChartPath == chartPath {
+			return &result.Schema, nil
+		}
+	}
+	return nil, fmt.Errorf("no schema generated for %s", chartPath)
+}
+
+// toJSONValue converts the schema into its generic json representation
+func toJSONValue(s *Schema) (interface{}, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// diffJSON compares two generic json values. Objects are compared key by key and arrays of schemas
+// (e.g. of anyOf) item by item. The required keys are compared regardless of their order, everything
+// else as a whole.
+func diffJSON(path string, old, new interface{}) []Change {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		oldItems, oldIsSchemas := schemaArray(old)
+		newItems, newIsSchemas := schemaArray(new)
+		if oldIsSchemas && newIsSchemas {
+			return diffJSONArray(path, oldItems, newItems)
+		}
+		if reflect.DeepEqual(old, new) || (strings.HasSuffix(path, "/required") && sameStringSet(old, new)) {
+			return nil
+		}
+		return []Change{{Path: path, Old: old, New: new}}
+	}
+
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for key := range oldMap {
+		keys = append(keys, key)
+	}
+	for key := range newMap {
+		if _, ok := oldMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []Change{}
+	for _, key := range keys {
+		changes = append(changes, diffJSON(path+"/"+escapeJSONPointer(key), oldMap[key], newMap[key])...)
+	}
+	return changes
+}
+
+// diffJSONArray compares two arrays of schemas item by item
+func diffJSONArray(path string, old, new []interface{}) []Change {
+	changes := []Change{}
+	for i := 0; i < len(old) || i < len(new); i++ {
+		var oldItem, newItem interface{}
+		if i < len(old) {
+			oldItem = old[i]
+		}
+		if i < len(new) {
+			newItem = new[i]
+		}
+		changes = append(changes, diffJSON(path+"/"+strconv.Itoa(i), oldItem, newItem)...)
+	}
+	return changes
+}
+
+// schemaArray returns the items of the given json value, if it's a non-empty array of objects
+func schemaArray(value interface{}) ([]interface{}, bool) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	for _, item := range items {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return items, true
+}
+
+// sameStringSet reports whether both json values are arrays of the same strings, regardless of their order
+func sameStringSet(a, b interface{}) bool {
+	aItems, aOk := a.([]interface{})
+	bItems, bOk := b.([]interface{})
+	if !aOk || !bOk {
+		return false
+	}
+	aSet, aOk := stringSet(aItems)
+	bSet, bOk := stringSet(bItems)
+	return aOk && bOk && reflect.DeepEqual(aSet, bSet)
+}
+
+// stringSet returns the set of the given strings, if all items are strings
+func stringSet(items []interface{}) (map[string]bool, bool) {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		set[s] = true
+	}
+	return set, true
+}
+
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestGenerateAndCheck(t *testing.T) {
+	dir := t.TempDir()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	schemaPath := filepath.Join(dir, "values.schema.json")
+
+	values := `
+replicas: 1
+name: foo
+`
+	if err := os.WriteFile(valuesPath, []byte(values), 0644); err != nil {
+		t.Fatal(err)
+	}
+	committed, err := GenerateFromReader(strings.NewReader(values), GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(committed, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemaPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, diff, err := GenerateAndCheck(valuesPath, schemaPath, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, changed, false)
+	assert.Equal(t, len(diff), 0)
+
+	// the values changed, but the committed schema didn't
+	if err := os.WriteFile(valuesPath, []byte("replicas: one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, diff, err = GenerateAndCheck(valuesPath, schemaPath, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, changed, true)
+	paths := []string{}
+	for _, change := range diff {
+		paths = append(paths, change.Path)
+	}
+	assert.Equal(t, paths, []string{"/properties/name", "/properties/replicas/default", "/properties/replicas/type", "/required"})
+	assert.Equal(t, diff[0].New, nil)
+	assert.Equal(t, diff[2], Change{Path: "/properties/replicas/type", Old: "integer", New: "string"})
+}
+
+func TestGenerateAndCheckRelativeRef(t *testing.T) {
+	dir := t.TempDir()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	schemaPath := filepath.Join(dir, "values.schema.json")
+	if err := os.WriteFile(filepath.Join(dir, "port.json"), []byte(`{"type": "integer", "minimum": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	values := "# @schema\n# $ref: port.json\n# @schema\nport: 80\n"
+	if err := os.WriteFile(valuesPath, []byte(values), 0644); err != nil {
+		t.Fatal(err)
+	}
+	committed, err := GenerateFromReader(strings.NewReader(values), GenerateOptions{ValuesPath: valuesPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(committed, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemaPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ref is resolved relative to the values file, not the working directory
+	changed, diff, err := GenerateAndCheck(valuesPath, schemaPath, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, diff, []Change{})
+	assert.Equal(t, changed, false)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old             string
+		new             string
+		expectedChanges []Change
+	}{
+		{
+			// the order of the required keys doesn't matter
+			old:             `{"required": ["a", "b"]}`,
+			new:             `{"required": ["b", "a"]}`,
+			expectedChanges: []Change{},
+		},
+		{
+			old:             `{"required": ["a", "b"]}`,
+			new:             `{"required": ["a"]}`,
+			expectedChanges: []Change{{Path: "/required", Old: []interface{}{"a", "b"}, New: []interface{}{"a"}}},
+		},
+		{
+			// the order of enums does
+			old:             `{"enum": ["a", "b"]}`,
+			new:             `{"enum": ["b", "a"]}`,
+			expectedChanges: []Change{{Path: "/enum", Old: []interface{}{"a", "b"}, New: []interface{}{"b", "a"}}},
+		},
+		{
+			old: `{"anyOf": [{"type": "string"}, {"type": "integer", "minimum": 1}]}`,
+			new: `{"anyOf": [{"type": "string"}, {"type": "integer", "minimum": 0}, {"type": "null"}]}`,
+			expectedChanges: []Change{
+				{Path: "/anyOf/1/minimum", Old: float64(1), New: float64(0)},
+				{Path: "/anyOf/2", Old: nil, New: map[string]interface{}{"type": "null"}},
+			},
+		},
+		{
+			old:             `{"items": [{"type": "string", "required": ["a", "b"]}]}`,
+			new:             `{"items": [{"type": "string", "required": ["b", "a"]}]}`,
+			expectedChanges: []Change{},
+		},
+	}
+
+	for _, test := range tests {
+		var old, new interface{}
+		if err := json.Unmarshal([]byte(test.old), &old); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(test.new), &new); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, diffJSON("", old, new), test.expectedChanges)
+	}
+}
@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Draft is a version of the jsonschema specification
+type Draft int
+
+const (
+	DraftUnknown Draft = iota
+	Draft04
+	Draft07
+	Draft201909
+	Draft202012
+)
+
+// DefaultSchemaURI is the $schema of the generated schemas, helm only supports draft-07
+const DefaultSchemaURI = "http://json-schema.org/draft-07/schema#"
+
+var draftsBySchemaURI = map[string]Draft{
+	"json-schema.org/draft-04/schema":      Draft04,
+	"json-schema.org/draft-07/schema":      Draft07,
+	"json-schema.org/draft/2019-09/schema": Draft201909,
+	"json-schema.org/draft/2020-12/schema": Draft202012,
+}
+
+// DraftFromSchemaURI returns the draft identified by the given $schema URI
+func DraftFromSchemaURI(uri string) Draft {
+	normalized := strings.TrimSuffix(uri, "#")
+	normalized = strings.TrimPrefix(normalized, "http://")
+	normalized = strings.TrimPrefix(normalized, "https://")
+	if draft, ok := draftsBySchemaURI[normalized]; ok {
+		return draft
+	}
+	return DraftUnknown
+}
+
+// draft04Keywords maps keywords to their draft-04 names
+var draft04Keywords = map[string]string{
+	"$id":   "id",
+	"$defs": "definitions",
+}
+
+var (
+	// keywords whose value is a map of schemas
+	schemaMapKeywords = []string{"properties", "patternProperties", "$defs", "definitions", "dependencies", "dependentSchemas"}
+	// keywords whose value is a schema or a list of schemas
+	schemaKeywords = []string{
+		"items", "prefixItems", "additionalItems", "additionalProperties", "unevaluatedProperties", "propertyNames",
+		"anyOf", "allOf", "oneOf", "not", "if", "then", "else", "contains",
+	}
+)
+
+// marshalDraft04 marshals the given json schema converted to draft-04
+func marshalDraft04(data map[string]interface{}) ([]byte, error) {
+	output, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	schema := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, err
+	}
+	toDraft04(schema)
+	return json.Marshal(schema)
+}
+
+// forEachJSONSchema calls fn for the subschemas of the given json schema and then for the schema itself
+func forEachJSONSchema(schema map[string]interface{}, fn func(map[string]interface{}) error) error {
+	for key, value := range schema {
+		switch {
+		case slices.Contains(schemaMapKeywords, key):
+			if subSchemas, ok := value.(map[string]interface{}); ok {
+				for _, subSchema := range subSchemas {
+					if subSchema, ok := subSchema.(map[string]interface{}); ok {
+						if err := forEachJSONSchema(subSchema, fn); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		case slices.Contains(schemaKeywords, key):
+			switch subSchema := value.(type) {
+			case map[string]interface{}:
+				if err := forEachJSONSchema(subSchema, fn); err != nil {
+					return err
+				}
+			case []interface{}:
+				for _, item := range subSchema {
+					if item, ok := item.(map[string]interface{}); ok {
+						if err := forEachJSONSchema(item, fn); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return fn(schema)
+}
+
+// toDraft04 converts the given json schema (and its subschemas) to draft-04. Keywords are renamed
+// (e.g. $id becomes id), refs to $defs point to the definitions, numeric exclusive bounds become
+// boolean flags of the bounds and empty required arrays are removed, as draft-04 doesn't allow them.
+func toDraft04(schema map[string]interface{}) {
+	forEachJSONSchema(schema, func(schema map[string]interface{}) error {
+		if ref, ok := schema["$ref"].(string); ok && strings.HasPrefix(ref, "#/$defs/") {
+			schema["$ref"] = "#/definitions/" + strings.TrimPrefix(ref, "#/$defs/")
+		}
+		for exclusiveKeyword, keyword := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+			if bound, ok := schema[exclusiveKeyword].(json.Number); ok {
+				schema[keyword] = bound
+				schema[exclusiveKeyword] = true
+			}
+		}
+		if required, ok := schema["required"].([]interface{}); ok && len(required) == 0 {
+			delete(schema, "required")
+		}
+		for keyword, draft04Keyword := range draft04Keywords {
+			if value, ok := schema[keyword]; ok {
+				delete(schema, keyword)
+				schema[draft04Keyword] = value
+			}
+		}
+		return nil
+	})
+}
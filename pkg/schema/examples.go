@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// addFileExamples adds the values of the ExampleFiles as examples of the matching keys
+func (o *GenerateOptions) addFileExamples(s *Schema) error {
+	for _, exampleFile := range o.ExampleFiles {
+		if valuesPath := o.valuesPath(); !filepath.IsAbs(exampleFile) && valuesPath != "" {
+			exampleFile = filepath.Join(filepath.Dir(valuesPath), exampleFile)
+		}
+		content, err := os.ReadFile(exampleFile)
+		if errors.Is(err, os.ErrNotExist) {
+			o.warnf("The example file %s doesn't exist", exampleFile)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var values interface{}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return fmt.Errorf("%s: %w", exampleFile, err)
+		}
+		o.addValueExamples(s, values)
+	}
+	return nil
+}
+
+// secretKeys returns the pattern of the keys whose values aren't published as examples. Those are the SecretKeys
+// of the inference, or the default ones if the inference of secrets is disabled.
+func (o *GenerateOptions) secretKeys() *regexp.Regexp {
+	if o.Inference.SecretKeys != nil {
+		return o.Inference.SecretKeys
+	}
+	return defaultSecretKeyMatcher
+}
+
+// addValueExamples adds the given value as example of the schema. The values of maps are added to the
+// matching properties instead, if the schema has any. Sensitive values (of writeOnly or secret keys) are skipped.
+func (o *GenerateOptions) addValueExamples(s *Schema, value interface{}) {
+	if s == nil || value == nil || s.WriteOnly {
+		return
+	}
+	if values, ok := value.(map[string]interface{}); ok && len(s.Properties) > 0 {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			if !o.secretKeys().MatchString(key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			o.addValueExamples(s.Properties[key], values[key])
+		}
+		return
+	}
+	value = o.withoutSecrets(value)
+
+	// the default is already shown as such
+	if reflect.DeepEqual(s.Default, value) {
+		return
+	}
+	for _, example := range s.Examples {
+		if reflect.DeepEqual(example, value) {
+			return
+		}
+	}
+	s.Examples = append(s.Examples, value)
+}
+
+// withoutSecrets returns the given value without the entries of secret keys in its (nested) maps
+func (o *GenerateOptions) withoutSecrets(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			if !o.secretKeys().MatchString(key) {
+				result[key] = o.withoutSecrets(nested)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = o.withoutSecrets(item)
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package schema
+
+import "regexp"
+
+// ToFlatSchema creates a schema validating flat, dotted keys like the ones used by helm's
+// --set option (e.g. --set image.tag=latest). Every leaf property becomes a patternProperties
+// entry matching its dotted path, array items can additionally be set by index (e.g. hosts[0])
+// and the keys of maps without properties (e.g. extraLabels.app) by any nested path.
+func (s *Schema) ToFlatSchema() *Schema {
+	flat := NewSchema("object")
+	flat.Schema = s.Schema
+	flat.PatternProperties = make(map[string]*Schema)
+	flat.AdditionalProperties = false
+	s.addFlatPatternProperties(flat.PatternProperties, "")
+	return flat
+}
+
+func (s *Schema) addFlatPatternProperties(patternProperties map[string]*Schema, prefix string) {
+	for key, property := range s.Properties {
+		path := regexp.QuoteMeta(key)
+		if prefix != "" {
+			path = prefix + `\.` + path
+		}
+
+		if len(property.Properties) > 0 {
+			property.addFlatPatternProperties(patternProperties, path)
+			continue
+		}
+
+		leaf := *property
+		leaf.Required = NewBoolOrArrayOfString([]string{}, false)
+		patternProperties["^"+path+"$"] = &leaf
+		if property.Items != nil {
+			patternProperties["^"+path+`\[[0-9]+\]$`] = property.Items
+		}
+		if additional := property.additionalPropertiesSchema(); additional != nil {
+			patternProperties["^"+path+`\..+$`] = additional
+		}
+	}
+}
+
+// additionalPropertiesSchema returns the schema of the keys of a map, which aren't one of its properties.
+// It's nil if the schema isn't a map or doesn't allow such keys.
+func (s *Schema) additionalPropertiesSchema() *Schema {
+	if !s.Type.Matches("object") {
+		return nil
+	}
+	if closed, ok := s.UnevaluatedProperties.(bool); ok && !closed {
+		return nil
+	}
+	switch additional := s.AdditionalProperties.(type) {
+	case *Schema:
+		return additional
+	case bool:
+		if !additional {
+			return nil
+		}
+	case *bool:
+		if additional != nil && !*additional {
+			return nil
+		}
+	}
+	return &Schema{}
+}
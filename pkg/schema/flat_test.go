@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func TestToFlatSchema(t *testing.T) {
+	s := &Schema{
+		Type: StringOrArrayOfString{"object"},
+		Properties: map[string]*Schema{
+			"a": {
+				Type: StringOrArrayOfString{"object"},
+				Properties: map[string]*Schema{
+					"b": {Type: StringOrArrayOfString{"integer"}},
+				},
+			},
+			"hosts": {
+				Type:  StringOrArrayOfString{"array"},
+				Items: &Schema{Type: StringOrArrayOfString{"string"}},
+			},
+			"global": {Type: StringOrArrayOfString{"object"}},
+			"labels": {
+				Type:                 StringOrArrayOfString{"object"},
+				AdditionalProperties: &Schema{Type: StringOrArrayOfString{"string"}},
+			},
+			"image": {
+				Type:                 StringOrArrayOfString{"object"},
+				AdditionalProperties: new(bool),
+			},
+		},
+	}
+
+	flat := s.ToFlatSchema()
+	assert.Equal(t, len(flat.PatternProperties), 8)
+	assert.Equal(t, flat.PatternProperties[`^a\.b$`].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, flat.PatternProperties[`^hosts$`].Type, StringOrArrayOfString{"array"})
+	assert.Equal(t, flat.PatternProperties[`^hosts\[[0-9]+\]$`].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, flat.AdditionalProperties, false)
+	// the keys of free-form maps and maps with an additionalProperties schema
+	assert.Equal(t, flat.PatternProperties[`^global\..+$`], &Schema{})
+	assert.Equal(t, flat.PatternProperties[`^labels\..+$`].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, flat.PatternProperties[`^image\..+$`] == nil, true)
+
+	if err := flat.Validate(); err != nil {
+		t.Errorf("Expected the flat schema to be valid, but got: %v", err)
+	}
+	output, err := flat.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(string(output))); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	instances := []struct {
+		values        map[string]interface{}
+		expectedValid bool
+	}{
+		{values: map[string]interface{}{"a.b": 1, "hosts[0]": "example.org"}, expectedValid: true},
+		{values: map[string]interface{}{"global.imageRegistry": "example.org", "global.nested.key": true}, expectedValid: true},
+		{values: map[string]interface{}{"labels.app": "foo"}, expectedValid: true},
+		{values: map[string]interface{}{"labels.app": 1}, expectedValid: false},
+		{values: map[string]interface{}{"image.tag": "latest"}, expectedValid: false},
+	}
+	for _, instance := range instances {
+		err := compiled.Validate(instance.values)
+		if valid := err == nil; valid != instance.expectedValid {
+			t.Errorf("Expected the values %v to be valid=%t, but it's %t (%v)", instance.values, instance.expectedValid, valid, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// builtinFormats are the string formats defined by jsonschema
+// https://json-schema.org/understanding-json-schema/reference/string.html#built-in-formats
+// We currently dont support https://datatracker.ietf.org/doc/html/rfc3339#appendix-A
+var builtinFormats = []string{
+	"date-time", "time", "date", "duration",
+	"email", "idn-email",
+	"hostname", "idn-hostname",
+	"ipv4", "ipv6",
+	"uuid", "uri", "uri-reference", "iri", "iri-reference", "uri-template",
+	"json-pointer", "relative-json-pointer",
+	"regex",
+}
+
+var (
+	customFormatsMutex sync.RWMutex
+	// customFormats maps the names of the registered formats to their pattern, which is empty if they have none
+	customFormats = map[string]string{}
+)
+
+// RegisterFormat adds a custom string format (e.g. semver), which is accepted by Validate in addition to
+// the built-in formats
+func RegisterFormat(name string) {
+	customFormatsMutex.Lock()
+	defer customFormatsMutex.Unlock()
+	if _, ok := customFormats[name]; !ok {
+		customFormats[name] = ""
+	}
+}
+
+// RegisterFormatPattern adds a custom string format like RegisterFormat. Schemas using the format are emitted with
+// the given regex as pattern (in an allOf, if they have another one), so validators which don't know the format
+// check the values too.
+func RegisterFormatPattern(name, pattern string) error {
+	if name == "" {
+		return errors.New("the name of a format can't be empty")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return err
+	}
+	customFormatsMutex.Lock()
+	defer customFormatsMutex.Unlock()
+	customFormats[name] = pattern
+	return nil
+}
+
+// unregisterFormat removes a registered format
+func unregisterFormat(name string) {
+	customFormatsMutex.Lock()
+	defer customFormatsMutex.Unlock()
+	delete(customFormats, name)
+}
+
+// isKnownFormat returns true if the format is a built-in or a registered one
+func isKnownFormat(name string) bool {
+	if slices.Contains(builtinFormats, name) {
+		return true
+	}
+	customFormatsMutex.RLock()
+	defer customFormatsMutex.RUnlock()
+	_, ok := customFormats[name]
+	return ok
+}
+
+// formatPattern returns the pattern of a registered format, or an empty string if it has none
+func formatPattern(name string) string {
+	customFormatsMutex.RLock()
+	defer customFormatsMutex.RUnlock()
+	return customFormats[name]
+}
@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	s := &Schema{Type: StringOrArrayOfString{"string"}, Format: "k8s-name"}
+	if err := s.Validate(); err == nil {
+		t.Fatal("Expected an error for an unregistered format")
+	}
+
+	RegisterFormat("k8s-name")
+	t.Cleanup(func() { unregisterFormat("k8s-name") })
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the registered format to be valid, but got: %v", err)
+	}
+
+	t.Cleanup(func() { unregisterFormat("semver") })
+	if err := RegisterFormatPattern("semver", "(invalid"); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+	if err := RegisterFormatPattern("semver", `^\d+\.\d+\.\d+$`); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s = &Schema{Type: StringOrArrayOfString{"string"}, Format: "semver"}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the registered format to be valid, but got: %v", err)
+	}
+
+	// the pattern of the format is emitted with it
+	data, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, out["format"], "semver")
+	assert.Equal(t, out["pattern"], `^\d+\.\d+\.\d+$`)
+
+	// another pattern is kept, the one of the format is added to the allOf
+	s.Pattern = `^1\.`
+	data, err = s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	out = map[string]interface{}{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, out["pattern"], `^1\.`)
+	assert.Equal(t, out["allOf"], []interface{}{map[string]interface{}{"pattern": `^\d+\.\d+\.\d+$`}})
+}
@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/rsafonseca/helm-schema/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateFromReader reads the values from the given reader and creates the jsonschema, without requiring
+// the values to be a file. Relative file refs are resolved against the ValuesPath or BaseDir of the options.
+func GenerateFromReader(r io.Reader, opts GenerateOptions) (*Schema, error) {
+	content, err := util.ReadFileAndFixNewline(r)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ReplaceTemplates {
+		content = util.ReplaceTemplateExpressions(content)
+	}
+
+	schema, err := generateDocuments(opts, content)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.completeSchema(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// generateDocuments creates the jsonschema for the yaml documents of the given values, multiple documents
+// are handled according to the MultiDocumentHandling
+func generateDocuments(opts GenerateOptions, content []byte) (*Schema, error) {
+	documents, err := decodeDocuments(content)
+	if err != nil {
+		// the error of the parser doesn't make tab indentation obvious
+		if tabErr := util.CheckTabIndentation(content); tabErr != nil {
+			return nil, tabErr
+		}
+		return nil, err
+	}
+
+	switch {
+	case len(documents) == 0:
+		return generate(opts, &yaml.Node{})
+	case len(documents) == 1 || opts.MultiDocumentHandling == MultiDocumentFirst:
+		return generate(opts, documents[0])
+	case opts.MultiDocumentHandling != MultiDocumentAnyOf:
+		return nil, fmt.Errorf(
+			"found %d yaml documents in the values, use the multi document handling %s or %s to process them",
+			len(documents),
+			MultiDocumentFirst,
+			MultiDocumentAnyOf,
+		)
+	}
+
+	combined := &Schema{multiDocument: true}
+	seen := make(map[string]bool)
+	for _, document := range documents {
+		documentSchema, err := generate(opts, document)
+		if err != nil {
+			return nil, err
+		}
+		// the dialect, $id and $defs belong to the combined root schema
+		combined.Schema, documentSchema.Schema = documentSchema.Schema, ""
+		if combined.Id == "" {
+			combined.Id = documentSchema.Id
+		}
+		documentSchema.Id = ""
+		for name, def := range documentSchema.Defs {
+			if combined.Defs == nil {
+				combined.Defs = make(map[string]*Schema)
+			}
+			combined.Defs[name] = def
+		}
+		documentSchema.Defs = nil
+
+		// identical schemas add nothing to the anyOf
+		documentJSON, err := documentSchema.ToJson()
+		if err != nil {
+			return nil, err
+		}
+		if seen[string(documentJSON)] {
+			continue
+		}
+		seen[string(documentJSON)] = true
+		combined.AnyOf = append(combined.AnyOf, documentSchema)
+	}
+	if len(combined.AnyOf) == 1 {
+		single := combined.AnyOf[0]
+		single.Schema, single.Id, single.Defs = combined.Schema, combined.Id, combined.Defs
+		return single, nil
+	}
+	return combined, nil
+}
+
+// Documents returns the schemas of the yaml documents the schema was generated from. That's the schema itself,
+// unless it combines the schemas of multiple documents.
+func (s *Schema) Documents() []*Schema {
+	if s.multiDocument {
+		return s.AnyOf
+	}
+	return []*Schema{s}
+}
+
+// decodeDocuments returns the yaml documents (separated by ---) of the given content. Empty documents
+// (e.g. after a trailing --- or with only comments) are dropped, unless all of them are empty.
+func decodeDocuments(content []byte) ([]*yaml.Node, error) {
+	var documents, empty []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isEmptyDocument(&document) {
+			empty = append(empty, &document)
+			continue
+		}
+		documents = append(documents, &document)
+	}
+	if len(documents) == 0 && len(empty) > 0 {
+		// the comments of an empty document can still annotate the root schema
+		return empty[:1], nil
+	}
+	return documents, nil
+}
+
+// isEmptyDocument returns true if the document has no content or only a null value
+func isEmptyDocument(document *yaml.Node) bool {
+	if len(document.Content) == 0 {
+		return true
+	}
+	value := document.Content[0]
+	return value.Kind == yaml.ScalarNode && value.Tag == nullTag
+}
+
+// Generate creates the jsonschema for the given values node. If FailOnWarning is set, an error listing the
+// warnings of the generation is returned.
+func Generate(opts GenerateOptions, node *yaml.Node) (*Schema, error) {
+	schema, err := generate(opts, node)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.completeSchema(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// generate creates the jsonschema for the given values node like Generate, without completing the root schema
+func generate(opts GenerateOptions, node *yaml.Node) (*Schema, error) {
+	generateOpts := opts.withWarnings()
+	schema, err := yamlToSchema(node, generateOpts, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if opts.TranslationsFile != "" {
+		if err := generateOpts.applyTranslations(schema); err != nil {
+			return nil, err
+		}
+	}
+	if err := generateOpts.addFileExamples(schema); err != nil {
+		return nil, err
+	}
+	if err := generateOpts.warningsError(); err != nil {
+		return nil, err
+	}
+	if opts.ValidateDefaults {
+		violations, err := schema.DefaultViolations()
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 {
+			return nil, fmt.Errorf("found %d invalid default(s) or example(s):\n%s", len(violations), strings.Join(violations, "\n"))
+		}
+	}
+	return schema, nil
+}
+
+// GenerateSubtree creates the jsonschema for the given values and returns the subschema found at
+// the dotted path (e.g. "foo.bar"). The $id of the subschema is resolved against the $ids of its
+// parents, so the subschema can be used on its own.
+func GenerateSubtree(r io.Reader, atPath string, opts GenerateOptions) (*Schema, error) {
+	root, err := GenerateFromReader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if atPath == "" {
+		return root, nil
+	}
+
+	current := root
+	id := root.Id
+	for _, key := range strings.Split(atPath, ".") {
+		next, ok := current.Properties[key]
+		if !ok {
+			return nil, fmt.Errorf("no schema found for %s at path %s", key, atPath)
+		}
+		id, err = resolveId(id, next.Id)
+		if err != nil {
+			return nil, err
+		}
+		current = next
+	}
+
+	subtree := *current
+	subtree.Id = id
+	// the subtree might target another draft than the rest of the schema
+	if subtree.Schema == "" {
+		subtree.Schema = root.Schema
+	}
+	return &subtree, nil
+}
+
+// relativeId makes the given id relative to the base id, if both share the same location
+func relativeId(base, id string) string {
+	baseUrl, err := url.Parse(base)
+	if err != nil || !baseUrl.IsAbs() {
+		return id
+	}
+	idUrl, err := url.Parse(id)
+	if err != nil || !idUrl.IsAbs() || idUrl.Scheme != baseUrl.Scheme || idUrl.Host != baseUrl.Host {
+		return id
+	}
+
+	if idUrl.Path == baseUrl.Path {
+		if idUrl.Fragment == "" {
+			return ""
+		}
+		return "#" + idUrl.Fragment
+	}
+
+	// ids in the same "directory" as the base (or below it) can be relative
+	baseDir := baseUrl.Path[:strings.LastIndex(baseUrl.Path, "/")+1]
+	if !strings.HasPrefix(idUrl.Path, baseDir) {
+		return id
+	}
+	relative := &url.URL{Path: strings.TrimPrefix(idUrl.Path, baseDir), Fragment: idUrl.Fragment}
+	return relative.String()
+}
+
+// resolveId resolves the (maybe relative) id against the given base id
+func resolveId(base, id string) (string, error) {
+	if id == "" {
+		return base, nil
+	}
+	if base == "" {
+		return id, nil
+	}
+	baseUrl, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	idUrl, err := url.Parse(id)
+	if err != nil {
+		return "", err
+	}
+	return baseUrl.ResolveReference(idUrl).String(), nil
+}
@@ -0,0 +1,851 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateSubtree(t *testing.T) {
+	data := `
+# @schema
+# $id: https://example.org/schemas/foo/
+# @schema
+foo:
+  # @schema
+  # $id: bar.json
+  # @schema
+  bar:
+    # @schema
+    # $id: baz.json
+    # @schema
+    baz:
+      enabled: true
+  other: 1
+`
+	subtree, err := GenerateSubtree(strings.NewReader(data), "foo.bar", GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, subtree.Id, "https://example.org/schemas/foo/bar.json")
+	assert.Equal(t, subtree.Schema, "http://json-schema.org/draft-07/schema#")
+	// nested ids stay relative to the rebased id
+	assert.Equal(t, subtree.Properties["baz"].Id, "baz.json")
+
+	if _, err := GenerateSubtree(strings.NewReader(data), "foo.missing", GenerateOptions{}); err == nil {
+		t.Errorf("Expected an error for a missing path")
+	}
+}
+
+func TestBaseURI(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "port.json"), []byte(`{"type": "integer", "minimum": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := `
+# @schema
+# $id: https://example.org/schemas/image.json
+# @schema
+image:
+  tag: latest
+# @schema
+# $id: https://example.org/schemas/nested/replicas.json
+# @schema
+replicas: 1
+# @schema
+# $id: https://example.org/schemas/app/app.json
+# @schema
+app:
+  # @schema
+  # $id: https://example.org/schemas/app/service.json
+  # @schema
+  service:
+    # @schema
+    # $id: ports/port.json
+    # @schema
+    port: 80
+# @schema
+# $id: https://other.org/foo.json
+# @schema
+other: foo
+# @schema
+# $ref: port.json
+# @schema
+port: 80
+`
+	opts := &GenerateOptions{ValuesPath: filepath.Join(dir, "values.yaml"), BaseURI: "https://example.org/schemas/values.json"}
+	s := generateTestSchema(t, data, opts)
+
+	assert.Equal(t, s.Id, "https://example.org/schemas/values.json")
+	assert.Equal(t, s.Properties["image"].Id, "image.json")
+	assert.Equal(t, s.Properties["replicas"].Id, "nested/replicas.json")
+	assert.Equal(t, s.Properties["other"].Id, "https://other.org/foo.json")
+	// nested ids are relative to the id of their parent
+	assert.Equal(t, s.Properties["app"].Id, "app/app.json")
+	assert.Equal(t, s.Properties["app"].Properties["service"].Id, "service.json")
+	assert.Equal(t, s.Properties["app"].Properties["service"].Properties["port"].Id, "ports/port.json")
+	// relative file refs are still resolved
+	assert.Equal(t, s.Properties["port"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, *s.Properties["port"].Minimum, 1)
+
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+}
+
+func TestRefPreservesDefs(t *testing.T) {
+	dir := t.TempDir()
+	network := `{
+  "$defs": {"port": {"type": "integer", "minimum": 1}},
+  "properties": {"service": {"type": "object", "properties": {"port": {"$ref": "#/$defs/port"}}}}
+}`
+	if err := os.WriteFile(filepath.Join(dir, "network.json"), []byte(network), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := `
+# @schema
+# $ref: network.json#/properties/service
+# @schema
+service:
+  port: 80
+`
+	s := generateTestSchema(t, data, &GenerateOptions{ValuesPath: filepath.Join(dir, "values.yaml")})
+
+	assert.Equal(t, s.Properties["service"].Properties["port"].Ref, "#/$defs/network.port")
+	assert.Equal(t, s.Defs["network.port"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, *s.Defs["network.port"].Minimum, 1)
+
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+}
+
+func TestNestedRelativeRefs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"schemas/service.json": `{
+  "properties": {
+    "service": {
+      "type": "object",
+      "properties": {"port": {"$ref": "../common/port.json", "description": "the port of the service"}}
+    }
+  }
+}`,
+		"common/port.json": `{
+  "$defs": {"range": {"minimum": 1, "maximum": 65535}},
+  "type": "integer",
+  "allOf": [{"$ref": "#/$defs/range"}]
+}`,
+		"cycle/a.json": `{"properties": {"b": {"$ref": "b.json"}}}`,
+		"cycle/b.json": `{"properties": {"a": {"$ref": "a.json"}}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := `
+# @schema
+# $ref: schemas/service.json#/properties/service
+# @schema
+service:
+  port: 80
+`
+	valuesPath := filepath.Join(dir, "values.yaml")
+	s := generateTestSchema(t, data, &GenerateOptions{ValuesPath: valuesPath})
+
+	// port.json is resolved relative to service.json
+	port := s.Properties["service"].Properties["port"]
+	assert.Equal(t, port.Ref, "")
+	assert.Equal(t, port.Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, port.Description, "the port of the service")
+	assert.Equal(t, port.AllOf[0].Ref, "#/$defs/common_port.range")
+	assert.Equal(t, *s.Defs["common_port.range"].Maximum, 65535)
+
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+
+	_, err := (&GenerateOptions{}).loadRef(valuesPath, valuesPath, "cycle/a.json", nil)
+	if err == nil || !strings.Contains(err.Error(), "circular $ref") {
+		t.Errorf("Expected an error because of the circular refs, but got: %v", err)
+	}
+}
+
+func TestReplaceTemplates(t *testing.T) {
+	data := `
+# @schema
+# type: string
+# @schema
+name: {{ .Release.Name }}-svc
+replicas: 1
+`
+	s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{ReplaceTemplates: true})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["name"].Default, "${HELM_TEMPLATE}-svc")
+	assert.Equal(t, s.Properties["replicas"].Type, StringOrArrayOfString{"integer"})
+
+	// the placeholder can be dropped by the placeholder handling
+	s, err = GenerateFromReader(strings.NewReader("name: {{ .Release.Name }}\n"), GenerateOptions{
+		ReplaceTemplates:    true,
+		PlaceholderHandling: PlaceholderOmitDefault,
+	})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["name"].Default, nil)
+
+	if _, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{}); err == nil {
+		t.Errorf("Expected an error for templated values without replacing the templates")
+	}
+}
+
+func TestPerSubtreeSchema(t *testing.T) {
+	data := `
+# @schema
+# $schema: https://json-schema.org/draft/2020-12/schema
+# $id: https://example.org/schemas/legacy.json
+# @schema
+legacy:
+  foo: 1
+current:
+  bar: 1
+`
+	root, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, root.Schema, "http://json-schema.org/draft-07/schema#")
+	assert.Equal(t, root.Properties["legacy"].Schema, "https://json-schema.org/draft/2020-12/schema")
+	assert.Equal(t, root.Properties["current"].Schema, "")
+
+	output, err := root.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"$schema": "https://json-schema.org/draft/2020-12/schema"`) {
+		t.Errorf("Expected the $schema of the subtree in the output, but got:\n%s", output)
+	}
+
+	subtree, err := GenerateSubtree(strings.NewReader(data), "legacy", GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, subtree.Schema, "https://json-schema.org/draft/2020-12/schema")
+
+	subtree, err = GenerateSubtree(strings.NewReader(data), "current", GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, subtree.Schema, "http://json-schema.org/draft-07/schema#")
+}
+
+func TestPostProcess(t *testing.T) {
+	data := `
+foo:
+  bar: 1
+list:
+  - name: baz
+`
+	var annotate func(s *Schema)
+	annotate = func(s *Schema) {
+		if s.Type.Matches("object") {
+			if s.CustomAnnotations == nil {
+				s.CustomAnnotations = map[string]interface{}{}
+			}
+			s.CustomAnnotations["x-org"] = "acme"
+		}
+		for _, subSchema := range s.subSchemas() {
+			annotate(subSchema)
+		}
+	}
+	calls := 0
+	opts := GenerateOptions{PostProcess: func(s *Schema) error {
+		calls++
+		annotate(s)
+		return nil
+	}}
+
+	s, err := GenerateFromReader(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, calls, 1)
+	for _, object := range []*Schema{s, s.Properties["foo"], s.Properties["global"], s.Properties["list"].Items} {
+		assert.Equal(t, object.CustomAnnotations["x-org"], "acme")
+	}
+	assert.Equal(t, s.Properties["foo"].Properties["bar"].CustomAnnotations["x-org"], nil)
+
+	// the hook gets the combined schema of multiple documents
+	calls = 0
+	opts.MultiDocumentHandling = MultiDocumentAnyOf
+	s, err = GenerateFromReader(strings.NewReader(data+"---\nother: true\n"), opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, calls, 1)
+	assert.Equal(t, len(s.AnyOf), 2)
+}
+
+func TestFailOnWarning(t *testing.T) {
+	data := `
+# @schema
+# enum: [a, b, c]
+# @schema
+size: a
+# @schema
+# type: array
+# items:
+#   type: string
+# @schema
+names: [foo, 1]
+`
+	opts := GenerateOptions{MaxEnumSize: 2, CheckItemsConsistency: true}
+	if _, err := GenerateFromReader(strings.NewReader(data), opts); err != nil {
+		t.Fatalf("Wasn't expecting an error without FailOnWarning, but got this: %v", err)
+	}
+
+	opts.FailOnWarning = true
+	_, err := GenerateFromReader(strings.NewReader(data), opts)
+	if err == nil {
+		t.Fatal("Expected an error, because of the warnings")
+	}
+	assert.Matches(t, err.Error(), `produced 2 warning`)
+	assert.Matches(t, err.Error(), `The enum of key size has 3 members`)
+	assert.Matches(t, err.Error(), `Item 1 of key names has type integer`)
+
+	// the warnings of a generation don't leak into the next one
+	if _, err := GenerateFromReader(strings.NewReader("foo: bar\n"), opts); err != nil {
+		t.Errorf("Wasn't expecting an error, but got this: %v", err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "port.json"), []byte(`{"type": "integer", "minimum": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := `
+# @schema
+# $ref: port.json
+# @schema
+port: 80
+# -- the name
+name: foo
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+	s, err := Generate(GenerateOptions{
+		ValuesPath:               filepath.Join(dir, "values.yaml"),
+		DontRemoveHelmDocsPrefix: true,
+		SkipAutoGeneration:       &SkipAutoGenerationConfig{Title: true},
+	}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	assert.Equal(t, *s.Properties["port"].Minimum, 1)
+	assert.Equal(t, s.Properties["name"].Description, "-- the name")
+	assert.Equal(t, s.Properties["name"].Title, "")
+
+	// without skipped fields
+	s, err = Generate(GenerateOptions{ValuesPath: filepath.Join(dir, "values.yaml")}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["name"].Title, "name")
+	assert.Equal(t, s.Properties["name"].Description, "the name")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		values        string
+		expectedError string
+	}{
+		{
+			values:        "# @schema\n# type: [\n# @schema\nfoo: bar\n",
+			expectedError: "error while parsing comment of key foo",
+		},
+		{
+			values:        "foo:\n  # @schema\n  # minimum: foo\n  # @schema\n  bar: 1\n",
+			expectedError: "error while parsing comment of key bar",
+		},
+		{
+			values:        "# @schema\n# type: integer\n# const: 1\n# default: 2\n# @schema\nfoo: 1\n",
+			expectedError: "error while validating jsonschema of key foo",
+		},
+		{
+			values:        "items:\n  - # @schema\n    # $ref: missing.json\n    # @schema\n    foo: bar\n",
+			expectedError: "missing.json",
+		},
+	}
+
+	for _, test := range tests {
+		// the errors are returned, instead of exiting
+		_, err := GenerateFromReader(strings.NewReader(test.values), GenerateOptions{ValuesPath: filepath.Join(t.TempDir(), "values.yaml")})
+		if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf("Expected an error containing %q for %q, but got: %v", test.expectedError, test.values, err)
+		}
+	}
+}
+
+func TestSkipGlobal(t *testing.T) {
+	data := "foo: bar\n"
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+
+	s, err := Generate(GenerateOptions{}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["global"].Type, StringOrArrayOfString{"object"})
+
+	skipAutoGeneration, err := NewSkipAutoGenerationConfig([]string{"global"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err = Generate(GenerateOptions{SkipAutoGeneration: skipAutoGeneration}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	_, ok := s.Properties["global"]
+	assert.Equal(t, ok, false)
+	assert.Equal(t, s.Properties["foo"].Type, StringOrArrayOfString{"string"})
+}
+
+func TestSchemaURI(t *testing.T) {
+	data := "foo: bar\n"
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+
+	s, err := Generate(GenerateOptions{}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Schema, DefaultSchemaURI)
+
+	s, err = Generate(GenerateOptions{SchemaURI: "https://json-schema.org/draft/2020-12/schema"}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Schema, "https://json-schema.org/draft/2020-12/schema")
+	assert.Equal(t, DraftFromSchemaURI(s.Schema), Draft202012)
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+	assert.Equal(t, DraftFromSchemaURI("https://json-schema.org/draft/2019-09/schema"), Draft201909)
+}
+
+func TestTuple(t *testing.T) {
+	data := `
+# @schema
+# tuple: true
+# @schema
+endpoint: [localhost, 8080]
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+
+	// draft 2020-12 uses prefixItems
+	s, err := Generate(GenerateOptions{SchemaURI: "https://json-schema.org/draft/2020-12/schema"}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	endpoint := s.Properties["endpoint"]
+	assert.Equal(t, len(endpoint.PrefixItems), 2)
+	assert.Equal(t, endpoint.PrefixItems[0].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, endpoint.PrefixItems[1].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, endpoint.Items == nil, true)
+	output, err := endpoint.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Matches(t, string(output), `"prefixItems": \[`)
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+
+	// draft-07 uses a list of items
+	s, err = Generate(GenerateOptions{}, &node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	endpoint = s.Properties["endpoint"]
+	assert.Equal(t, len(endpoint.TupleItems), 2)
+	assert.Equal(t, len(endpoint.PrefixItems), 0)
+
+	// prefixItems are only allowed for arrays
+	invalid, _, err := GetSchemaFromComment(`
+# @schema
+# type: object
+# prefixItems:
+#   - type: string
+# @schema`)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected prefixItems without type=array to be invalid")
+	}
+}
+
+func TestZeroValueDefaults(t *testing.T) {
+	data := `
+enabled: false
+replicas: 0
+name: ""
+# @schema
+# default: false
+# @schema
+debug: true
+`
+	s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["enabled"].Default, false)
+	assert.Equal(t, s.Properties["replicas"].Default, 0)
+	assert.Equal(t, s.Properties["name"].Default, "")
+	assert.Equal(t, s.Properties["debug"].Default, false)
+
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, expected := range []string{`"default": false`, `"default": 0`, `"default": ""`} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected %s in the output, but got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestMultiDocument(t *testing.T) {
+	data := `
+# @schema
+# minimum: 1
+# @schema
+replicas: 1
+---
+replicas: 1
+autoscaling: true
+---
+replicas: 1
+autoscaling: true
+`
+	// multiple documents fail by default
+	if _, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{}); err == nil {
+		t.Error("Expected an error for values with multiple documents")
+	}
+
+	s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{MultiDocumentHandling: MultiDocumentFirst})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, *s.Properties["replicas"].Minimum, 1)
+	assert.Equal(t, s.Properties["autoscaling"] == nil, true)
+
+	s, err = GenerateFromReader(strings.NewReader(data), GenerateOptions{MultiDocumentHandling: MultiDocumentAnyOf})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Schema, DefaultSchemaURI)
+	// identical documents are only used once
+	assert.Equal(t, len(s.AnyOf), 2)
+	assert.Equal(t, s.Documents(), s.AnyOf)
+	assert.Equal(t, s.AnyOf[0].Schema, "")
+	assert.Equal(t, *s.AnyOf[0].Properties["replicas"].Minimum, 1)
+	assert.Equal(t, s.AnyOf[1].Properties["autoscaling"].Type, StringOrArrayOfString{"boolean"})
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+
+	// a single document isn't combined
+	s, err = GenerateFromReader(strings.NewReader("---\nreplicas: 1\n"), GenerateOptions{MultiDocumentHandling: MultiDocumentAnyOf})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.AnyOf == nil, true)
+	assert.Equal(t, s.Documents(), []*Schema{s})
+
+	// empty documents (e.g. after a trailing ---) are ignored
+	for _, data := range []string{"replicas: 1\n---\n", "replicas: 1\n---\n# only a comment\n", "---\nreplicas: 1\n---\n~\n"} {
+		s, err = GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error for %q, but got this: %v", data, err)
+		}
+		assert.Equal(t, s.Properties["replicas"].Type, StringOrArrayOfString{"integer"})
+	}
+}
+
+func TestMultiDocumentValidation(t *testing.T) {
+	// the documents only differ in their defaults, the values match both of them
+	data := `
+# -- number of replicas
+replicas: 1
+---
+# -- number of replicas
+replicas: 3
+autoscaling: true
+`
+	s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{MultiDocumentHandling: MultiDocumentAnyOf})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, len(s.AnyOf), 2)
+
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(string(output))); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	instances := []struct {
+		values        string
+		expectedValid bool
+	}{
+		{values: `{"replicas": 1}`, expectedValid: true},
+		{values: `{"replicas": 3}`, expectedValid: true},
+		{values: `{"replicas": 2, "autoscaling": false}`, expectedValid: true},
+		{values: `{"replicas": "2"}`, expectedValid: false},
+		{values: `{"replicas": 2, "autoscaling": "yes"}`, expectedValid: false},
+	}
+	for _, instance := range instances {
+		var values interface{}
+		if err := json.Unmarshal([]byte(instance.values), &values); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		err := compiled.Validate(values)
+		if valid := err == nil; valid != instance.expectedValid {
+			t.Errorf("Expected the values %s to be valid=%t, but it's %t (%v)", instance.values, instance.expectedValid, valid, err)
+		}
+	}
+}
+
+func TestReproducibleOutput(t *testing.T) {
+	data := `
+zone: a
+region: eu
+image:
+  tag: latest
+  repository: nginx
+  pullPolicy: Always
+replicas: 1
+autoscaling:
+  enabled: false
+`
+	var outputs []string
+	for i := 0; i < 5; i++ {
+		s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		out, err := s.ToJson()
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		outputs = append(outputs, string(out))
+	}
+	for _, out := range outputs[1:] {
+		assert.Equal(t, out, outputs[0])
+	}
+
+	// properties are sorted by their name
+	var last int
+	for _, key := range []string{`"autoscaling"`, `"image"`, `"region"`, `"replicas"`, `"zone"`} {
+		index := strings.Index(outputs[0], key)
+		if index < last {
+			t.Errorf("Expected %s to follow the previous properties in the output", key)
+		}
+		last = index
+	}
+}
+
+func TestExampleFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"values-staging.yaml": "replicas: 2\nimage:\n  tag: v1\nlevel: info\n",
+		"values-prod.yaml":    "replicas: 5\nimage:\n  tag: v1\nresources: {cpu: 2}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Error while writing test data: %v", err)
+		}
+	}
+	data := `
+replicas: 1
+image:
+  tag: latest
+level: info
+resources: {}
+`
+	opts := GenerateOptions{
+		ValuesPath:   filepath.Join(dir, "values.yaml"),
+		ExampleFiles: []string{"values-staging.yaml", "values-prod.yaml", "values-missing.yaml"},
+	}
+	s, err := GenerateFromReader(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["replicas"].Examples, []interface{}{2, 5})
+	// the examples are deduplicated
+	assert.Equal(t, s.Properties["image"].Properties["tag"].Examples, []interface{}{"v1"})
+	// values equal to the default aren't added
+	assert.Equal(t, s.Properties["level"].Examples == nil, true)
+	// maps without properties get the whole value as example
+	assert.Equal(t, s.Properties["resources"].Examples, []interface{}{map[string]interface{}{"cpu": 2}})
+
+	// missing files are warnings
+	opts.FailOnWarning = true
+	_, err = GenerateFromReader(strings.NewReader(data), opts)
+	if err == nil || !strings.Contains(err.Error(), "values-missing.yaml doesn't exist") {
+		t.Errorf("Expected an error for the missing example file, but got: %v", err)
+	}
+}
+
+func TestExampleFilesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	example := "token: prod-token\nadminPassword: hunter2\nuser: admin\nextraEnv: {DB_PASSWORD: hunter2, DB_HOST: db}\n"
+	if err := os.WriteFile(filepath.Join(dir, "values-prod.yaml"), []byte(example), 0o644); err != nil {
+		t.Fatalf("Error while writing test data: %v", err)
+	}
+	data := `
+# @schema
+# writeOnly: true
+# @schema
+token: ""
+adminPassword: ""
+user: ""
+extraEnv: {}
+`
+	tests := []struct {
+		inference        InferenceOptions
+		expectedExamples map[string][]interface{}
+	}{
+		{
+			// the default secret keys are skipped, even if the inference of secrets is disabled
+			inference: InferenceOptions{},
+			expectedExamples: map[string][]interface{}{
+				"token":         nil,
+				"adminPassword": nil,
+				"user":          {"admin"},
+				"extraEnv":      {map[string]interface{}{"DB_HOST": "db"}},
+			},
+		},
+		{
+			inference: InferenceOptions{SecretKeys: regexp.MustCompile(`^user$`)},
+			expectedExamples: map[string][]interface{}{
+				"token":         nil,
+				"adminPassword": {"hunter2"},
+				"user":          nil,
+				"extraEnv":      {map[string]interface{}{"DB_HOST": "db", "DB_PASSWORD": "hunter2"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		opts := GenerateOptions{
+			ValuesPath:   filepath.Join(dir, "values.yaml"),
+			ExampleFiles: []string{"values-prod.yaml"},
+			Inference:    test.inference,
+		}
+		s, err := GenerateFromReader(strings.NewReader(data), opts)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		for key, examples := range test.expectedExamples {
+			assert.Equal(t, s.Properties[key].Examples, examples)
+		}
+	}
+}
+
+func TestGenerateFromReaderBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "port.json"), []byte(`{"type": "integer", "minimum": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := "# @schema\r\n# $ref: port.json\r\n# @schema\r\nport: 80\r\n"
+
+	s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{BaseDir: dir})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	// relative file refs are resolved against the base dir
+	assert.Equal(t, s.Properties["port"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, *s.Properties["port"].Minimum, 1)
+}
+
+func TestGenerateFromReaderTabIndentation(t *testing.T) {
+	data := "image:\n  repository: nginx\n\ttag: latest\n"
+	_, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+	if err == nil {
+		t.Fatal("Expected an error for values indented with a tab")
+	}
+	assert.Equal(t, strings.Contains(err.Error(), "line 3 is indented with a tab"), true)
+
+	// tabs are only reported if the values can't be parsed
+	data = "command: \"echo\n\tfoo\"\n"
+	if _, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{}); err != nil {
+		t.Errorf("Wasn't expecting an error, but got this: %v", err)
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	data := `
+# @schema
+# minimum: 10
+# examples: [20, 3]
+# @schema
+port: 5
+image:
+  # @schema
+  # enum: [latest, stable]
+  # @schema
+  tag: latest
+`
+	// the defaults aren't checked by default
+	s, err := GenerateFromReader(strings.NewReader(data), GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	violations, err := s.DefaultViolations()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, violations, []string{
+		"#/properties/port/default: must be >= 10 but found 5",
+		"#/properties/port/examples/1: must be >= 10 but found 3",
+	})
+
+	_, err = GenerateFromReader(strings.NewReader(data), GenerateOptions{ValidateDefaults: true})
+	if err == nil {
+		t.Fatal("Expected an error for the invalid default")
+	}
+	assert.Equal(t, strings.Contains(err.Error(), "#/properties/port/default"), true)
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSecretKeyPattern matches keys which likely contain sensitive values (e.g. adminPassword)
+const DefaultSecretKeyPattern = `(?i)(password|passwd|secret|token|apikey|api_key|credentials?)$`
+
+var defaultSecretKeyMatcher = regexp.MustCompile(DefaultSecretKeyPattern)
+
+// InferenceOptions gathers the opt-in heuristics, which derive more than the type from the values.
+// The zero value disables all of them, DefaultInferenceOptions enables all of them.
+type InferenceOptions struct {
+	// HumanizeTitles uses the words of the key as title (e.g. "Replica Count" for replicaCount) instead of the key
+	HumanizeTitles bool
+	// TimestampFormats adds format date or date-time to values with the yaml timestamp tag
+	TimestampFormats bool
+	// StringFormats adds format uri, email, ipv4 or date to string values which look like one of them
+	StringFormats bool
+	// SecretKeys matches the keys of sensitive string values. Those are writeOnly and get no default, so the
+	// values of the values file aren't published with the schema. Disabled if nil.
+	SecretKeys *regexp.Regexp
+	// KubernetesNameKeys matches the keys of string values which are kubernetes resource names. Those get
+	// the maxLength and pattern of a DNS-1123 subdomain, unless they're annotated or their value isn't one.
+	// Disabled if nil.
+	KubernetesNameKeys *regexp.Regexp
+	// RequiredIfNonEmpty only adds keys to the required keys of their parent, if their value isn't empty
+	// (null, "", {} or [])
+	RequiredIfNonEmpty bool
+	// NullHandling defines the type of keys with a null value
+	NullHandling NullHandling
+}
+
+// DefaultInferenceOptions returns the inference options with all heuristics enabled, using the default patterns
+// and the permissive null handling
+func DefaultInferenceOptions() InferenceOptions {
+	return InferenceOptions{
+		HumanizeTitles:     true,
+		TimestampFormats:   true,
+		StringFormats:      true,
+		SecretKeys:         defaultSecretKeyMatcher,
+		KubernetesNameKeys: regexp.MustCompile(DefaultKubernetesNameKeyPattern),
+		RequiredIfNonEmpty: true,
+		NullHandling:       NullPermissive,
+	}
+}
+
+// title returns the title of the given key
+func (i InferenceOptions) title(key string) string {
+	if !i.HumanizeTitles {
+		return key
+	}
+	return humanize(key)
+}
+
+// isSecret returns true if the key matches the SecretKeys and the schema is one of a string
+func (i InferenceOptions) isSecret(key string, s *Schema) bool {
+	return i.SecretKeys != nil && i.SecretKeys.MatchString(key) && s.Type.Matches("string")
+}
+
+// humanize splits the key into its words (at _, -, . and changes of the case) and capitalizes them,
+// e.g. image_pull_secrets or imagePullSecrets becomes "Image Pull Secrets"
+func humanize(key string) string {
+	runes := []rune(key)
+	words := []string{}
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i == len(runes) || runes[i] == '_' || runes[i] == '-' || runes[i] == '.' || runes[i] == ' ' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		// a new word starts at an upper case letter after a lower case one, or at the last upper case
+		// letter of an abbreviation followed by a lower case one (e.g. HTTPPort)
+		if !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	for i, word := range words {
+		first := []rune(word)
+		first[0] = unicode.ToUpper(first[0])
+		words[i] = string(first)
+	}
+	return strings.Join(words, " ")
+}
+
+// isEmptyValue returns true if the value is null, an empty string, map or sequence
+func isEmptyValue(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Tag == nullTag || (node.Tag == strTag && node.Value == "")
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	}
+	return false
+}
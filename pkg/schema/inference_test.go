@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHumanize(t *testing.T) {
+	tests := map[string]string{
+		"replicaCount":       "Replica Count",
+		"image_pull_secrets": "Image Pull Secrets",
+		"log-level":          "Log Level",
+		"HTTPPort":           "HTTP Port",
+		"apiURL":             "Api URL",
+		"enabled":            "Enabled",
+	}
+	for key, expected := range tests {
+		assert.Equal(t, humanize(key), expected)
+	}
+}
+
+func TestInferenceOptions(t *testing.T) {
+	data := `
+replicaCount: 1
+adminPassword: changeme
+serviceName: web
+nodeSelector: {}
+tolerations: []
+`
+
+	opts := &GenerateOptions{Inference: InferenceOptions{
+		HumanizeTitles:     true,
+		SecretKeys:         regexp.MustCompile(DefaultSecretKeyPattern),
+		RequiredIfNonEmpty: true,
+	}}
+	s := generateTestSchema(t, data, opts)
+
+	assert.Equal(t, s.Properties["replicaCount"].Title, "Replica Count")
+	// secrets are write-only and have no default
+	assert.Equal(t, s.Properties["adminPassword"].WriteOnly, true)
+	assert.Equal(t, s.Properties["adminPassword"].Default, nil)
+	assert.Equal(t, s.Properties["serviceName"].Default, "web")
+	// keys with empty values aren't required
+	assert.Equal(t, s.Required.Strings, []string{"replicaCount", "adminPassword", "serviceName"})
+	// the kubernetes name heuristic isn't enabled
+	assert.Equal(t, s.Properties["serviceName"].MaxLength == nil, true)
+
+	// all heuristics are enabled by default
+	defaults := DefaultInferenceOptions()
+	assert.Equal(t, defaults.KubernetesNameKeys.String(), DefaultKubernetesNameKeyPattern)
+	assert.Equal(t, defaults.NullHandling, NullPermissive)
+}
@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ToMarkdown creates a markdown table documenting all the (nested) properties of the schema
+func (s *Schema) ToMarkdown() string {
+	var b strings.Builder
+	b.WriteString("| Key | Type | Default | Description | Deprecated | Read only |\n")
+	b.WriteString("|-|-|-|-|-|-|\n")
+	s.writeMarkdownRows(&b, "")
+	return b.String()
+}
+
+func (s *Schema) writeMarkdownRows(b *strings.Builder, prefix string) {
+	keys := make([]string, 0, len(s.Properties))
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		property := s.Properties[key]
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		fmt.Fprintf(
+			b,
+			"| `%s` | %s | %s | %s | %s | %s |\n",
+			path,
+			markdownType(property.Type),
+			markdownDefault(property.Default),
+			markdownEscape(property.Description),
+			markdownFlag(property.Deprecated),
+			markdownFlag(property.ReadOnly),
+		)
+
+		property.writeMarkdownRows(b, path)
+	}
+}
+
+func markdownType(t StringOrArrayOfString) string {
+	if len(t) == 0 {
+		return ""
+	}
+	return "`" + strings.Join(t, "`, `") + "`"
+}
+
+func markdownDefault(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return "`" + markdownEscape(string(data)) + "`"
+}
+
+func markdownFlag(flag bool) string {
+	if flag {
+		return "yes"
+	}
+	return ""
+}
+
+func markdownEscape(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
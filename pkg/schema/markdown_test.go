@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestToMarkdown(t *testing.T) {
+	s := &Schema{
+		Type: StringOrArrayOfString{"object"},
+		Properties: map[string]*Schema{
+			"image": {
+				Type: StringOrArrayOfString{"object"},
+				Properties: map[string]*Schema{
+					"tag": {
+						Type:        StringOrArrayOfString{"string"},
+						Default:     "latest",
+						Description: "The image tag",
+						Deprecated:  true,
+					},
+				},
+			},
+			"status": {
+				Type:     StringOrArrayOfString{"string", "null"},
+				ReadOnly: true,
+			},
+			"replicas": {
+				Type:    StringOrArrayOfString{"integer"},
+				Default: 1,
+			},
+		},
+	}
+
+	expected := "| Key | Type | Default | Description | Deprecated | Read only |\n" +
+		"|-|-|-|-|-|-|\n" +
+		"| `image` | `object` |  |  |  |  |\n" +
+		"| `image.tag` | `string` | `\"latest\"` | The image tag | yes |  |\n" +
+		"| `replicas` | `integer` | `1` |  |  |  |\n" +
+		"| `status` | `string`, `null` |  |  |  | yes |\n"
+	assert.Equal(t, s.ToMarkdown(), expected)
+}
@@ -0,0 +1,86 @@
+package schema
+
+import "reflect"
+
+// mergeSchema copies all the set fields of src to dst. Fields which are already
+// set in dst are only overwritten if override is true. Custom annotations are merged key by key.
+func mergeSchema(dst, src *Schema, override bool) {
+	if src == nil {
+		return
+	}
+
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+	schemaType := dstValue.Type()
+
+	for i := 0; i < schemaType.NumField(); i++ {
+		field := schemaType.Field(i)
+		if !field.IsExported() || field.Name == "CustomAnnotations" {
+			continue
+		}
+		srcField := srcValue.Field(i)
+		dstField := dstValue.Field(i)
+		if srcField.IsZero() || (!dstField.IsZero() && !override) {
+			continue
+		}
+		dstField.Set(srcField)
+	}
+
+	mergeCustomAnnotations(dst, src, override)
+}
+
+// patchSchema deep-merges src over dst. Nested schemas (e.g. properties or items) which are set in both
+// are patched recursively, all other set fields of src override the ones of dst.
+func patchSchema(dst, src *Schema) {
+	if src == nil {
+		return
+	}
+
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+	schemaType := dstValue.Type()
+
+	for i := 0; i < schemaType.NumField(); i++ {
+		field := schemaType.Field(i)
+		if !field.IsExported() || field.Name == "CustomAnnotations" {
+			continue
+		}
+		srcField := srcValue.Field(i)
+		dstField := dstValue.Field(i)
+		if srcField.IsZero() {
+			continue
+		}
+		if !dstField.IsZero() {
+			switch srcSchema := srcField.Interface().(type) {
+			case *Schema:
+				patchSchema(dstField.Interface().(*Schema), srcSchema)
+				continue
+			case map[string]*Schema:
+				dstSchemas := dstField.Interface().(map[string]*Schema)
+				for key, subSchema := range srcSchema {
+					if dstSchema, ok := dstSchemas[key]; ok {
+						patchSchema(dstSchema, subSchema)
+					} else {
+						dstSchemas[key] = subSchema
+					}
+				}
+				continue
+			}
+		}
+		dstField.Set(srcField)
+	}
+
+	mergeCustomAnnotations(dst, src, true)
+}
+
+// mergeCustomAnnotations merges the custom annotations of src into dst key by key
+func mergeCustomAnnotations(dst, src *Schema, override bool) {
+	for key, value := range src.CustomAnnotations {
+		if dst.CustomAnnotations == nil {
+			dst.CustomAnnotations = make(map[string]interface{})
+		}
+		if _, ok := dst.CustomAnnotations[key]; !ok || override {
+			dst.CustomAnnotations[key] = value
+		}
+	}
+}
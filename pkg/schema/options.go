@@ -0,0 +1,331 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPlaceholderPattern matches environment-variable placeholders like ${FOO}
+const DefaultPlaceholderPattern = `^\$\{[A-Za-z_][A-Za-z0-9_]*\}$`
+
+var defaultPlaceholderMatcher = regexp.MustCompile(DefaultPlaceholderPattern)
+
+// DefaultKubernetesNameKeyPattern matches keys which likely contain kubernetes resource names (e.g. name,
+// fullnameOverride or serviceName), but not keys like hostname or username
+const DefaultKubernetesNameKeyPattern = `^((full)?name(Override)?|[a-z][a-zA-Z0-9]*[a-z0-9]Name)$`
+
+const (
+	// kubernetesNameMaxLength is the maximum length of a DNS-1123 subdomain
+	kubernetesNameMaxLength = 253
+	// kubernetesNamePattern matches DNS-1123 subdomains, empty strings are allowed for unset names
+	kubernetesNamePattern = `^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*)?$`
+)
+
+var kubernetesNameMatcher = regexp.MustCompile(kubernetesNamePattern)
+
+// PlaceholderHandling defines how string values matching the placeholder pattern are treated
+type PlaceholderHandling string
+
+const (
+	// PlaceholderKeep treats placeholders like any other value
+	PlaceholderKeep PlaceholderHandling = ""
+	// PlaceholderOmitDefault doesn't use placeholders as default value
+	PlaceholderOmitDefault PlaceholderHandling = "omit-default"
+	// PlaceholderAddPattern doesn't use placeholders as default value, but documents them with a pattern
+	PlaceholderAddPattern PlaceholderHandling = "pattern"
+)
+
+var possiblePlaceholderHandlings = []PlaceholderHandling{PlaceholderKeep, PlaceholderOmitDefault, PlaceholderAddPattern}
+
+// NewPlaceholderHandling parses the given placeholder handling name
+func NewPlaceholderHandling(name string) (PlaceholderHandling, error) {
+	for _, handling := range possiblePlaceholderHandlings {
+		if string(handling) == name {
+			return handling, nil
+		}
+	}
+	return PlaceholderKeep, fmt.Errorf("unsupported placeholder handling '%s'", name)
+}
+
+// EnumOverflow defines what happens to enums with more members than the configured maximum
+type EnumOverflow string
+
+const (
+	// EnumOverflowWarn keeps the enum, but logs a warning
+	EnumOverflowWarn EnumOverflow = ""
+	// EnumOverflowDrop removes the enum, so only the type of the value is validated
+	EnumOverflowDrop EnumOverflow = "drop"
+)
+
+var possibleEnumOverflows = []EnumOverflow{EnumOverflowWarn, EnumOverflowDrop}
+
+// NewEnumOverflow parses the given enum overflow name
+func NewEnumOverflow(name string) (EnumOverflow, error) {
+	for _, overflow := range possibleEnumOverflows {
+		if string(overflow) == name {
+			return overflow, nil
+		}
+	}
+	return EnumOverflowWarn, fmt.Errorf("unsupported enum overflow '%s'", name)
+}
+
+// NullHandling defines the type of keys with a null value (empty, ~ or null)
+type NullHandling string
+
+const (
+	// NullDrop omits the type of null values, so any value is allowed
+	NullDrop NullHandling = ""
+	// NullStrict keeps the type null, so only null is allowed
+	NullStrict NullHandling = "strict"
+	// NullPermissive omits the type of null values like NullDrop and also adds null to annotated types,
+	// so the null value stays valid
+	NullPermissive NullHandling = "permissive"
+)
+
+var possibleNullHandlings = []NullHandling{NullDrop, NullStrict, NullPermissive}
+
+// NewNullHandling parses the given null handling name
+func NewNullHandling(name string) (NullHandling, error) {
+	for _, handling := range possibleNullHandlings {
+		if string(handling) == name {
+			return handling, nil
+		}
+	}
+	return NullDrop, fmt.Errorf("unsupported null handling '%s'", name)
+}
+
+// MultiDocumentHandling defines how values files with multiple yaml documents (separated by ---) are handled
+type MultiDocumentHandling string
+
+const (
+	// MultiDocumentError fails for values files with multiple documents
+	MultiDocumentError MultiDocumentHandling = ""
+	// MultiDocumentFirst only uses the first document
+	MultiDocumentFirst MultiDocumentHandling = "first"
+	// MultiDocumentAnyOf generates a schema per document, the values must match any of them
+	MultiDocumentAnyOf MultiDocumentHandling = "any-of"
+)
+
+var possibleMultiDocumentHandlings = []MultiDocumentHandling{MultiDocumentError, MultiDocumentFirst, MultiDocumentAnyOf}
+
+// NewMultiDocumentHandling parses the given multi document handling name
+func NewMultiDocumentHandling(name string) (MultiDocumentHandling, error) {
+	for _, handling := range possibleMultiDocumentHandlings {
+		if string(handling) == name {
+			return handling, nil
+		}
+	}
+	return MultiDocumentError, fmt.Errorf("unsupported multi document handling '%s'", name)
+}
+
+// TypeInferer returns the type and optionally additional constraints (jsonschema keywords) for a yaml value.
+// If no type is returned, the type is inferred from the yaml tag.
+type TypeInferer func(node *yaml.Node) (StringOrArrayOfString, map[string]interface{}, error)
+
+// TagHandler returns the schema for a value with a custom yaml tag (e.g. !secret). The returned schema
+// defines the type and additional constraints, annotated keywords take precedence.
+type TagHandler func(node *yaml.Node) (*Schema, error)
+
+// GenerateOptions contains the options for the schema generation
+type GenerateOptions struct {
+	// ValuesPath is the path of the values file, relative file refs are resolved relative to it
+	ValuesPath string
+	// BaseDir is the directory relative file refs are resolved against, if the values aren't read from a file
+	// (ValuesPath is empty), e.g. when they're piped over stdin. Defaults to the working directory.
+	BaseDir string
+	// KeepFullComment keeps the leading comments of keys, which are separated by a blank line, in the description
+	KeepFullComment bool
+	// FootComments adds the comment following the value of a key (e.g. after a nested map) to its description
+	FootComments bool
+	// DontRemoveHelmDocsPrefix keeps the helm-docs prefix (# --) and tags (e.g. @default) in the description
+	DontRemoveHelmDocsPrefix bool
+	// SkipAutoGeneration disables the generation of some fields (e.g. title or default)
+	SkipAutoGeneration *SkipAutoGenerationConfig
+
+	// PlaceholderHandling defines what to do with values matching the PlaceholderPattern
+	PlaceholderHandling PlaceholderHandling
+	// PlaceholderPattern matches placeholder values, defaults to DefaultPlaceholderPattern
+	PlaceholderPattern *regexp.Regexp
+	// Inference configures the opt-in heuristics (e.g. formats or titles), see DefaultInferenceOptions
+	Inference InferenceOptions
+	// CheckItemsConsistency warns if the values of a sequence don't match the type of its annotated items
+	CheckItemsConsistency bool
+	// TypeInferer is consulted before the type is inferred from the yaml tag
+	TypeInferer TypeInferer
+	// SchemaURI is the $schema (dialect) of the root schema, defaults to DefaultSchemaURI. An annotated
+	// $schema of the document takes precedence.
+	SchemaURI string
+	// BaseURI is used as $id of the root schema, nested $ids below it are made relative to the $id of their parent
+	BaseURI string
+	// Title is used as title of the root schema, unless the values annotate one
+	Title string
+	// UnevaluatedProperties uses unevaluatedProperties instead of additionalProperties to disallow
+	// unknown keys in composed schemas (allOf or $ref), so the properties of the subschemas are allowed. It requires
+	// a $schema of draft 2019-09 or newer, older drafts fall back to additionalProperties with a warning.
+	UnevaluatedProperties bool
+	// ReplaceTemplates replaces helm template expressions ({{ ... }}) in the values before parsing them,
+	// see util.ReplaceTemplateExpressions
+	ReplaceTemplates bool
+	// HelmDocsTitle uses the first sentence (or line) of a helm-docs comment (# -- ) as title and the rest
+	// of it as description, instead of using the key as title
+	HelmDocsTitle bool
+	// ReadOnlyNotRequired never adds readOnly keys to the required keys of their parent
+	ReadOnlyNotRequired bool
+	// MaxEnumSize is the maximum number of enum members, larger enums are handled according to EnumOverflow.
+	// Unlimited if 0.
+	MaxEnumSize int
+	// EnumOverflow defines what happens to enums with more than MaxEnumSize members
+	EnumOverflow EnumOverflow
+	// EnumTitles emits enums with an x-enumDescriptions annotation (a label per member) as oneOf of consts,
+	// which are titled by the labels
+	EnumTitles bool
+	// StrictTypes warns about keys whose type can't be inferred, because their value is empty and their
+	// annotation doesn't define a type
+	StrictTypes bool
+	// PostProcess is called once with the generated root schema, after the schemas of multiple documents are
+	// combined and the title is set, but before the schema is written. It can transform the schema, e.g. to add
+	// custom annotations.
+	PostProcess func(*Schema) error
+	// MultiDocumentHandling defines how values with multiple yaml documents are handled
+	MultiDocumentHandling MultiDocumentHandling
+	// ExampleFiles are values files (e.g. of environments), whose values are added as examples of the matching keys.
+	// Relative paths are resolved relative to the ValuesPath (or BaseDir), files which don't exist are skipped
+	// with a warning. The values of writeOnly and secret keys aren't added.
+	ExampleFiles []string
+	// CoerceExamples converts examples written as strings to the type of non-string values (e.g. "1" to 1)
+	CoerceExamples bool
+	// FailOnWarning turns the warnings of the generation (e.g. type mismatches or oversized enums) into an error,
+	// which lists all of them
+	FailOnWarning bool
+	// TranslationsFile is a yaml file mapping locales to the localized descriptions of the properties, which are
+	// keyed by their dotted path. The descriptions of the Locale replace the generated ones. Relative paths are
+	// resolved relative to the ValuesPath (or BaseDir), a file which doesn't exist is skipped with a warning.
+	TranslationsFile string
+	// Locale selects the translations of the TranslationsFile
+	Locale string
+	// ValidateDefaults checks that the defaults and examples are valid against the schema of their key, the
+	// generation fails for violations (e.g. a default below the annotated minimum)
+	ValidateDefaults bool
+	// ZeroDefaults uses the zero value of the annotated type (e.g. 0 or "") as default of keys with a null value
+	ZeroDefaults bool
+	// SortSetDefaults sorts the default of keys annotated with set: true, so the output doesn't depend on the
+	// order of the items
+	SortSetDefaults bool
+
+	// TagHandlers are consulted for values with the given yaml tags (e.g. "!secret") after the TypeInferer and
+	// before the type is inferred from the yaml tag
+	TagHandlers map[string]TagHandler
+
+	// hoistedDefs collects the $defs of referenced files during the generation
+	hoistedDefs map[string]*Schema
+	// warnings collects the warnings of the generation, if not nil
+	warnings *[]string
+}
+
+// withWarnings returns a copy of the options, which collects the warnings of a single generation
+func (o *GenerateOptions) withWarnings() *GenerateOptions {
+	opts := *o
+	opts.warnings = &[]string{}
+	return &opts
+}
+
+// warnf logs the warning and collects it
+func (o *GenerateOptions) warnf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Warn(message)
+	if o.warnings != nil {
+		*o.warnings = append(*o.warnings, message)
+	}
+}
+
+// warningsError returns an error listing the collected warnings, if FailOnWarning is set
+func (o *GenerateOptions) warningsError() error {
+	if !o.FailOnWarning || o.warnings == nil || len(*o.warnings) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the generation produced %d warning(s):\n%s", len(*o.warnings), strings.Join(*o.warnings, "\n"))
+}
+
+// completeSchema sets the Title on the generated root schema, unless the values annotate one, and calls the
+// PostProcess hook with it
+func (o *GenerateOptions) completeSchema(s *Schema) error {
+	if s.Title == "" {
+		s.Title = o.Title
+	}
+	if o.PostProcess == nil {
+		return nil
+	}
+	if err := o.PostProcess(s); err != nil {
+		return fmt.Errorf("error while post-processing the schema: %w", err)
+	}
+	return nil
+}
+
+// addKubernetesNameConstraints limits string values with a kubernetes name key to DNS-1123 subdomains. Keys whose
+// value isn't a DNS-1123 subdomain are skipped with a warning, they likely aren't kubernetes names.
+func (o *GenerateOptions) addKubernetesNameConstraints(key string, value *yaml.Node, s *Schema) {
+	if o.Inference.KubernetesNameKeys == nil || !o.Inference.KubernetesNameKeys.MatchString(key) || !s.Type.Matches("string") {
+		return
+	}
+	if value.Kind == yaml.ScalarNode && value.Tag == strTag &&
+		(len(value.Value) > kubernetesNameMaxLength || !kubernetesNameMatcher.MatchString(value.Value)) {
+		o.warnf("The value of key %s isn't a kubernetes resource name, so it doesn't get the constraints of one", key)
+		return
+	}
+	if s.MaxLength == nil {
+		maxLength := kubernetesNameMaxLength
+		s.MaxLength = &maxLength
+	}
+	if s.Pattern == "" && s.Format == "" {
+		s.Pattern = kubernetesNamePattern
+	}
+}
+
+// disallowAdditionalProperties disallows keys which aren't defined in the given schema
+func (o *GenerateOptions) disallowAdditionalProperties(s *Schema) {
+	if o.UnevaluatedProperties && (len(s.AllOf) > 0 || s.Ref != "") {
+		if s.UnevaluatedProperties == nil {
+			s.UnevaluatedProperties = false
+		}
+		return
+	}
+	s.AdditionalProperties = new(bool)
+}
+
+// valuesPath returns the path relative file refs are resolved against, that's the ValuesPath or a values file
+// in the BaseDir
+func (o *GenerateOptions) valuesPath() string {
+	if o.ValuesPath == "" && o.BaseDir != "" {
+		return filepath.Join(o.BaseDir, "values.yaml")
+	}
+	return o.ValuesPath
+}
+
+// skipAutoGeneration returns the configured SkipAutoGenerationConfig, by default nothing is skipped
+func (o *GenerateOptions) skipAutoGeneration() *SkipAutoGenerationConfig {
+	if o.SkipAutoGeneration == nil {
+		return &SkipAutoGenerationConfig{}
+	}
+	return o.SkipAutoGeneration
+}
+
+// schemaURI returns the configured $schema or the default one
+func (o *GenerateOptions) schemaURI() string {
+	if o.SchemaURI != "" {
+		return o.SchemaURI
+	}
+	return DefaultSchemaURI
+}
+
+// placeholderPattern returns the configured placeholder pattern or the default one
+func (o *GenerateOptions) placeholderPattern() *regexp.Regexp {
+	if o.PlaceholderPattern != nil {
+		return o.PlaceholderPattern
+	}
+	return defaultPlaceholderMatcher
+}
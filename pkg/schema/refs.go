@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dadav/go-jsonpointer"
+	"github.com/rsafonseca/helm-schema/pkg/util"
+)
+
+// localRefPrefixes are the prefixes of refs pointing to the $defs of the same file
+var localRefPrefixes = []string{"#/$defs/", "#/definitions/"}
+
+var nonNamespaceChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// defsNamespace returns the namespace for the hoisted $defs of the referenced file, e.g. schemas_port for ../schemas/port.json
+func defsNamespace(refPath string) string {
+	namespace := strings.TrimSuffix(refPath, filepath.Ext(refPath))
+	return strings.Trim(nonNamespaceChars.ReplaceAllString(namespace, "_"), "_")
+}
+
+// loadRef reads the schema referenced by a relative file ref (e.g. port.json#/properties/port), the file is
+// resolved relative to the file at basePath. Relative file refs of the referenced schema are resolved recursively
+// relative to it, the $defs of all referenced files are hoisted. Returns nil if the ref isn't a relative file.
+func (o *GenerateOptions) loadRef(valuesPath, basePath, ref string, visiting []string) (interface{}, error) {
+	refParts := strings.SplitN(ref, "#", 2)
+	if refParts[0] == "" {
+		return nil, nil
+	}
+	schemaPath, err := util.IsRelativeFile(basePath, refParts[0])
+	if err != nil {
+		return nil, nil
+	}
+	if slices.Contains(visiting, schemaPath) {
+		return nil, fmt.Errorf("circular $ref %s in %s", ref, basePath)
+	}
+	visiting = append(visiting, schemaPath)
+
+	byteValue, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(byteValue) == 0 {
+		return nil, nil
+	}
+	var obj interface{}
+	if err := json.Unmarshal(byteValue, &obj); err != nil {
+		return nil, fmt.Errorf("%s: %w", schemaPath, err)
+	}
+
+	// local refs of the referenced file point to its $defs, which are hoisted into the root schema
+	namespace := defsNamespace(schemaPath)
+	if relPath, err := filepath.Rel(filepath.Dir(valuesPath), schemaPath); err == nil {
+		namespace = defsNamespace(relPath)
+	}
+	obj = rewriteLocalRefs(obj, namespace)
+	obj, err = o.resolveFileRefs(valuesPath, schemaPath, obj, visiting)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.hoistDefs(namespace, obj); err != nil {
+		return nil, err
+	}
+
+	if len(refParts) > 1 {
+		// Found json-pointer
+		obj, err = jsonpointer.Get(obj, refParts[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// resolveFileRefs inlines the relative file refs of the given json value. Keywords next to the $ref take
+// precedence over the ones of the referenced schema.
+func (o *GenerateOptions) resolveFileRefs(valuesPath, basePath string, value interface{}, visiting []string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		if ref, ok := v["$ref"].(string); ok {
+			referenced, err := o.loadRef(valuesPath, basePath, ref, visiting)
+			if err != nil {
+				return nil, err
+			}
+			if referencedMap, ok := referenced.(map[string]interface{}); ok {
+				for key, item := range referencedMap {
+					if key != "$defs" && key != "definitions" {
+						result[key] = item
+					}
+				}
+				v = maps.Clone(v)
+				delete(v, "$ref")
+			}
+		}
+		for key, item := range v {
+			resolved, err := o.resolveFileRefs(valuesPath, basePath, item, visiting)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := o.resolveFileRefs(valuesPath, basePath, item, visiting)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = resolved
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+// hoistDefs collects the $defs (or definitions) of the referenced file, so they can be added to the root schema.
+// The local refs of the file must be namespaced already.
+func (o *GenerateOptions) hoistDefs(namespace string, file interface{}) error {
+	fileMap, ok := file.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, keyword := range []string{"$defs", "definitions"} {
+		defs, ok := fileMap[keyword].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name, def := range defs {
+			var defSchema Schema
+			if err := fromJSONValue(def, &defSchema); err != nil {
+				return err
+			}
+			if o.hoistedDefs == nil {
+				o.hoistedDefs = make(map[string]*Schema)
+			}
+			o.hoistedDefs[namespace+"."+name] = &defSchema
+		}
+	}
+	return nil
+}
+
+// withHoistedDefs returns a copy of the schema including the hoisted $defs, so local refs to them can be resolved
+func (o *GenerateOptions) withHoistedDefs(s Schema) Schema {
+	if len(o.hoistedDefs) > 0 && s.Defs == nil {
+		s.Defs = o.hoistedDefs
+	}
+	return s
+}
+
+// rewriteLocalRefs namespaces all refs to local $defs of the given json value
+func rewriteLocalRefs(value interface{}, namespace string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				for _, prefix := range localRefPrefixes {
+					if strings.HasPrefix(ref, prefix) {
+						item = "#/$defs/" + namespace + "." + strings.TrimPrefix(ref, prefix)
+						break
+					}
+				}
+			}
+			result[key] = rewriteLocalRefs(item, namespace)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = rewriteLocalRefs(item, namespace)
+		}
+		return result
+	}
+	return value
+}
+
+// fromJSONValue converts the generic json value into the schema
+func fromJSONValue(value interface{}, s *Schema) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, s)
+}
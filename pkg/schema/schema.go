@@ -1,18 +1,22 @@
 package schema
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/dadav/go-jsonpointer"
 	"github.com/rsafonseca/helm-schema/pkg/util"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 	log "github.com/sirupsen/logrus"
@@ -28,6 +32,12 @@ const (
 	// CustomAnnotationPrefix marks custom annotations.
 	// custom annotations is a map of custom annotations. See introduction of custom annotation: https://json-schema.org/blog/posts/custom-annotations-will-continue
 	CustomAnnotationPrefix = "x-"
+
+	// CustomAnnotationsNestKey is the key under which custom annotations are emitted when nesting is enabled
+	CustomAnnotationsNestKey = "x-meta"
+
+	// EnumDescriptionsAnnotation holds a label per enum member, see GenerateOptions.EnumTitles
+	EnumDescriptionsAnnotation = "x-enumDescriptions"
 )
 
 const (
@@ -46,6 +56,9 @@ type SchemaOrBool interface{}
 type BoolOrArrayOfString struct {
 	Strings []string
 	Bool    bool
+
+	// annotated holds the entries of Strings which were explicitly set by the user
+	annotated []string
 }
 
 func NewBoolOrArrayOfString(arr []string, b bool) BoolOrArrayOfString {
@@ -62,6 +75,21 @@ func (s *BoolOrArrayOfString) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s.Strings)
 }
 
+// addAnnotated adds an explicitly required entry
+func (s *BoolOrArrayOfString) addAnnotated(name string) {
+	if !slices.Contains(s.Strings, name) {
+		s.Strings = append(s.Strings, name)
+	}
+	if !slices.Contains(s.annotated, name) {
+		s.annotated = append(s.annotated, name)
+	}
+}
+
+// resetToAnnotated removes all the inferred entries, only the explicitly required ones are kept
+func (s *BoolOrArrayOfString) resetToAnnotated() {
+	s.Strings = append([]string{}, s.annotated...)
+}
+
 func (s *BoolOrArrayOfString) UnmarshalYAML(value *yaml.Node) error {
 	var multi []string
 	if value.ShortTag() == arrayTag {
@@ -74,6 +102,7 @@ func (s *BoolOrArrayOfString) UnmarshalYAML(value *yaml.Node) error {
 			multi = append(multi, typeStr)
 		}
 		s.Strings = multi
+		s.annotated = slices.Clone(multi)
 	} else if value.ShortTag() == boolTag {
 		var single bool
 		err := value.Decode(&single)
@@ -87,6 +116,72 @@ func (s *BoolOrArrayOfString) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// UnmarshalJSON reads the required keys of existing schemas, all of them are explicitly required
+func (s *BoolOrArrayOfString) UnmarshalJSON(value []byte) error {
+	var multi []string
+	if err := json.Unmarshal(value, &multi); err == nil {
+		s.Strings = multi
+		s.annotated = slices.Clone(multi)
+		return nil
+	}
+	var single bool
+	if err := json.Unmarshal(value, &single); err != nil {
+		return fmt.Errorf("could not unmarshal %s to slice of string or bool", value)
+	}
+	s.Bool = single
+	return nil
+}
+
+// SchemaOrStringArray is an entry of dependencies, which is either a schema or a list of property names
+type SchemaOrStringArray struct {
+	Schema  *Schema
+	Strings []string
+}
+
+func (s SchemaOrStringArray) MarshalJSON() ([]byte, error) {
+	if s.Schema != nil {
+		return json.Marshal(s.Schema)
+	}
+	if s.Strings == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal(s.Strings)
+}
+
+func (s *SchemaOrStringArray) UnmarshalYAML(value *yaml.Node) error {
+	if value.ShortTag() == arrayTag {
+		var multi []string
+		if err := value.Decode(&multi); err != nil {
+			return err
+		}
+		s.Strings = multi
+		return nil
+	}
+	if value.ShortTag() != mapTag {
+		return fmt.Errorf("could not unmarshal %s to a schema or slice of string", value.Value)
+	}
+	var schema Schema
+	if err := value.Decode(&schema); err != nil {
+		return err
+	}
+	s.Schema = &schema
+	return nil
+}
+
+func (s *SchemaOrStringArray) UnmarshalJSON(value []byte) error {
+	var multi []string
+	if err := json.Unmarshal(value, &multi); err == nil {
+		s.Strings = multi
+		return nil
+	}
+	var schema Schema
+	if err := json.Unmarshal(value, &schema); err != nil {
+		return fmt.Errorf("could not unmarshal %s to a schema or slice of string", value)
+	}
+	s.Schema = &schema
+	return nil
+}
+
 type StringOrArrayOfString []string
 
 func (s *StringOrArrayOfString) UnmarshalYAML(value *yaml.Node) error {
@@ -170,7 +265,67 @@ func (s *StringOrArrayOfString) Matches(typeString string) bool {
 	return false
 }
 
-// MarshalJSON custom marshal method for Schema. It inlines the CustomAnnotations fields
+// CustomAnnotationsOutput controls how the custom annotations are emitted by MarshalJSON
+type CustomAnnotationsOutput struct {
+	// Nested emits all custom annotations in a single object under the CustomAnnotationsNestKey
+	Nested bool
+	// CamelCase transforms the annotation keys to camelCase (e.g. x-foo-bar becomes x-fooBar)
+	CamelCase bool
+}
+
+// apply emits the custom annotations (the keys with the CustomAnnotationPrefix) of the given json schema as
+// configured. Keys which are the same in camelCase (e.g. x-foo-bar and x-fooBar) can't be emitted together.
+func (o CustomAnnotationsOutput) apply(schema map[string]interface{}) error {
+	keys := []string{}
+	for key := range schema {
+		if strings.HasPrefix(key, CustomAnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	annotations := make(map[string]interface{}, len(keys))
+	originalKeys := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value := schema[key]
+		delete(schema, key)
+		originalKey := key
+		if o.CamelCase {
+			key = camelCaseAnnotationKey(key)
+		}
+		if collidingKey, ok := originalKeys[key]; ok {
+			return fmt.Errorf("the custom annotations %s and %s are both emitted as %s", collidingKey, originalKey, key)
+		}
+		originalKeys[key] = originalKey
+		annotations[key] = value
+	}
+
+	if o.Nested {
+		// nest the CustomAnnotations fields
+		if len(annotations) > 0 {
+			schema[CustomAnnotationsNestKey] = annotations
+		}
+		return nil
+	}
+	for key, value := range annotations {
+		schema[key] = value
+	}
+	return nil
+}
+
+// camelCaseAnnotationKey converts the part after the CustomAnnotationPrefix to camelCase
+func camelCaseAnnotationKey(key string) string {
+	name := strings.TrimPrefix(key, CustomAnnotationPrefix)
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return CustomAnnotationPrefix + strings.Join(parts, "")
+}
+
+// MarshalJSON custom marshal method for Schema. It inlines (or nests) the CustomAnnotations fields
 func (s *Schema) MarshalJSON() ([]byte, error) {
 	// Create a map to hold all the fields
 	type Alias Schema
@@ -183,17 +338,39 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 		return nil, err
 	}
 
-	// Unmarshal the JSON back into the map
-	if err := json.Unmarshal(aliasJSON, &data); err != nil {
+	// Unmarshal the JSON back into the map, numbers are kept as they are written (e.g. the multipleOf of nested schemas)
+	decoder := json.NewDecoder(bytes.NewReader(aliasJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
 		return nil, err
 	}
 
+	delete(data, "CustomAnnotations")
+	if s.TupleItems != nil {
+		data["items"] = s.TupleItems
+	}
+	// validators which don't know the custom format still check the values
+	if pattern := formatPattern(s.Format); pattern != "" && s.Pattern == "" {
+		data["pattern"] = pattern
+	} else if pattern != "" && pattern != s.Pattern {
+		// a schema can only have one pattern, the one of the format has to match as well
+		allOf, _ := data["allOf"].([]interface{})
+		data["allOf"] = append(allOf, map[string]interface{}{"pattern": pattern})
+	}
+	if s.MultipleOf != nil {
+		// json uses the exponent format for small floats, multipleOf is written exactly as annotated instead
+		data["multipleOf"] = json.Number(strconv.FormatFloat(*s.MultipleOf, 'f', -1, 64))
+	}
+
 	// inline the CustomAnnotations fields
 	for key, value := range s.CustomAnnotations {
 		data[key] = value
 	}
 
-	delete(data, "CustomAnnotations")
+	if DraftFromSchemaURI(s.Schema) == Draft04 {
+		// the subschemas added above (e.g. the tuple items) are converted as well
+		return marshalDraft04(data)
+	}
 
 	// Marshal the final map into JSON
 	return json.Marshal(data)
@@ -201,43 +378,72 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 
 // Schema struct contains yaml tags for reading, json for writing (creating the jsonschema)
 type Schema struct {
-	AdditionalProperties SchemaOrBool           `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
-	Default              interface{}            `yaml:"default,omitempty"              json:"default,omitempty"`
-	Then                 *Schema                `yaml:"then,omitempty"                 json:"then,omitempty"`
-	PatternProperties    map[string]*Schema     `yaml:"patternProperties,omitempty"    json:"patternProperties,omitempty"`
-	Properties           map[string]*Schema     `yaml:"properties,omitempty"           json:"properties,omitempty"`
-	If                   *Schema                `yaml:"if,omitempty"                   json:"if,omitempty"`
-	Minimum              *int                   `yaml:"minimum,omitempty"              json:"minimum,omitempty"`
-	MultipleOf           *int                   `yaml:"multipleOf,omitempty"           json:"multipleOf,omitempty"`
-	ExclusiveMaximum     *int                   `yaml:"exclusiveMaximum,omitempty"     json:"exclusiveMaximum,omitempty"`
-	Items                *Schema                `yaml:"items,omitempty"                json:"items,omitempty"`
-	ExclusiveMinimum     *int                   `yaml:"exclusiveMinimum,omitempty"     json:"exclusiveMinimum,omitempty"`
-	Maximum              *int                   `yaml:"maximum,omitempty"              json:"maximum,omitempty"`
-	Else                 *Schema                `yaml:"else,omitempty"                 json:"else,omitempty"`
-	Pattern              string                 `yaml:"pattern,omitempty"              json:"pattern,omitempty"`
-	Const                interface{}            `yaml:"const,omitempty"                json:"const,omitempty"`
-	Ref                  string                 `yaml:"$ref,omitempty"                 json:"$ref,omitempty"`
-	Schema               string                 `yaml:"$schema,omitempty"              json:"$schema,omitempty"`
-	Id                   string                 `yaml:"$id,omitempty"                  json:"$id,omitempty"`
-	Format               string                 `yaml:"format,omitempty"               json:"format,omitempty"`
-	Description          string                 `yaml:"description,omitempty"          json:"description,omitempty"`
-	Title                string                 `yaml:"title,omitempty"                json:"title,omitempty"`
-	Type                 StringOrArrayOfString  `yaml:"type,omitempty"                 json:"type,omitempty"`
-	AnyOf                []*Schema              `yaml:"anyOf,omitempty"                json:"anyOf,omitempty"`
-	AllOf                []*Schema              `yaml:"allOf,omitempty"                json:"allOf,omitempty"`
-	OneOf                []*Schema              `yaml:"oneOf,omitempty"                json:"oneOf,omitempty"`
-	Not                  *Schema                `yaml:"not,omitempty"                  json:"not,omitempty"`
-	Examples             []string               `yaml:"examples,omitempty"             json:"examples,omitempty"`
-	Enum                 []string               `yaml:"enum,omitempty"                 json:"enum,omitempty"`
-	HasData              bool                   `yaml:"-"                              json:"-"`
-	Deprecated           bool                   `yaml:"deprecated,omitempty"           json:"deprecated,omitempty"`
-	ReadOnly             bool                   `yaml:"readOnly,omitempty"             json:"readOnly,omitempty"`
-	WriteOnly            bool                   `yaml:"writeOnly,omitempty"            json:"writeOnly,omitempty"`
-	Required             BoolOrArrayOfString    `yaml:"required,omitempty"             json:"required,omitempty"`
-	CustomAnnotations    map[string]interface{} `yaml:"-"                              json:",omitempty"`
-	MinLength            *int                   `yaml:"minLength,omitempty"            json:"minLength,omitempty"`
-	MaxLength            *int                   `yaml:"maxLength,omitempty"            json:"maxLength,omitempty"`
-	Dependencies         *Schema                `yaml:"dependencies,omitempty"         json:"dependencies,omitempty"`
+	AdditionalProperties  SchemaOrBool                   `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+	UnevaluatedProperties SchemaOrBool                   `yaml:"unevaluatedProperties,omitempty" json:"unevaluatedProperties,omitempty"`
+	Default               interface{}                    `yaml:"default,omitempty"              json:"default,omitempty"`
+	Then                  *Schema                        `yaml:"then,omitempty"                 json:"then,omitempty"`
+	PatternProperties     map[string]*Schema             `yaml:"patternProperties,omitempty"    json:"patternProperties,omitempty"`
+	Properties            map[string]*Schema             `yaml:"properties,omitempty"           json:"properties,omitempty"`
+	If                    *Schema                        `yaml:"if,omitempty"                   json:"if,omitempty"`
+	Minimum               *int                           `yaml:"minimum,omitempty"              json:"minimum,omitempty"`
+	MultipleOf            *float64                       `yaml:"multipleOf,omitempty"           json:"multipleOf,omitempty"`
+	ExclusiveMaximum      *int                           `yaml:"exclusiveMaximum,omitempty"     json:"exclusiveMaximum,omitempty"`
+	Items                 *Schema                        `yaml:"items,omitempty"                json:"items,omitempty"`
+	TupleItems            []*Schema                      `yaml:"-"                              json:"-"`
+	PrefixItems           []*Schema                      `yaml:"prefixItems,omitempty"          json:"prefixItems,omitempty"`
+	Tuple                 bool                           `yaml:"tuple,omitempty"                json:"-"`
+	AdditionalItems       SchemaOrBool                   `yaml:"additionalItems,omitempty"      json:"additionalItems,omitempty"`
+	ExclusiveMinimum      *int                           `yaml:"exclusiveMinimum,omitempty"     json:"exclusiveMinimum,omitempty"`
+	Maximum               *int                           `yaml:"maximum,omitempty"              json:"maximum,omitempty"`
+	Else                  *Schema                        `yaml:"else,omitempty"                 json:"else,omitempty"`
+	Pattern               string                         `yaml:"pattern,omitempty"              json:"pattern,omitempty"`
+	Const                 interface{}                    `yaml:"const,omitempty"                json:"const,omitempty"`
+	Ref                   string                         `yaml:"$ref,omitempty"                 json:"$ref,omitempty"`
+	Schema                string                         `yaml:"$schema,omitempty"              json:"$schema,omitempty"`
+	Id                    string                         `yaml:"$id,omitempty"                  json:"$id,omitempty"`
+	Vocabulary            map[string]bool                `yaml:"$vocabulary,omitempty"          json:"$vocabulary,omitempty"`
+	Format                string                         `yaml:"format,omitempty"               json:"format,omitempty"`
+	Description           string                         `yaml:"description,omitempty"          json:"description,omitempty"`
+	Title                 string                         `yaml:"title,omitempty"                json:"title,omitempty"`
+	Comment               string                         `yaml:"$comment,omitempty"             json:"$comment,omitempty"`
+	Type                  StringOrArrayOfString          `yaml:"type,omitempty"                 json:"type,omitempty"`
+	AnyOf                 []*Schema                      `yaml:"anyOf,omitempty"                json:"anyOf,omitempty"`
+	AllOf                 []*Schema                      `yaml:"allOf,omitempty"                json:"allOf,omitempty"`
+	OneOf                 []*Schema                      `yaml:"oneOf,omitempty"                json:"oneOf,omitempty"`
+	Not                   *Schema                        `yaml:"not,omitempty"                  json:"not,omitempty"`
+	Examples              []interface{}                  `yaml:"examples,omitempty"             json:"examples,omitempty"`
+	Enum                  []interface{}                  `yaml:"enum,omitempty"                 json:"enum,omitempty"`
+	HasData               bool                           `yaml:"-"                              json:"-"`
+	Deprecated            bool                           `yaml:"deprecated,omitempty"           json:"deprecated,omitempty"`
+	ReadOnly              bool                           `yaml:"readOnly,omitempty"             json:"readOnly,omitempty"`
+	WriteOnly             bool                           `yaml:"writeOnly,omitempty"            json:"writeOnly,omitempty"`
+	Required              BoolOrArrayOfString            `yaml:"required,omitempty"             json:"required,omitempty"`
+	CustomAnnotations     map[string]interface{}         `yaml:"-"                              json:",omitempty"`
+	MinLength             *int                           `yaml:"minLength,omitempty"            json:"minLength,omitempty"`
+	MaxLength             *int                           `yaml:"maxLength,omitempty"            json:"maxLength,omitempty"`
+	MinItems              *int                           `yaml:"minItems,omitempty"             json:"minItems,omitempty"`
+	MaxItems              *int                           `yaml:"maxItems,omitempty"             json:"maxItems,omitempty"`
+	UniqueItems           bool                           `yaml:"uniqueItems,omitempty"          json:"uniqueItems,omitempty"`
+	MinProperties         *int                           `yaml:"minProperties,omitempty"        json:"minProperties,omitempty"`
+	MaxProperties         *int                           `yaml:"maxProperties,omitempty"        json:"maxProperties,omitempty"`
+	Contains              *Schema                        `yaml:"contains,omitempty"             json:"contains,omitempty"`
+	MinContains           *int                           `yaml:"minContains,omitempty"          json:"minContains,omitempty"`
+	MaxContains           *int                           `yaml:"maxContains,omitempty"          json:"maxContains,omitempty"`
+	Dependencies          map[string]SchemaOrStringArray `yaml:"dependencies,omitempty"         json:"dependencies,omitempty"`
+	DependentRequired     map[string][]string            `yaml:"dependentRequired,omitempty"    json:"dependentRequired,omitempty"`
+	Defs                  map[string]*Schema             `yaml:"$defs,omitempty"                json:"$defs,omitempty"`
+	PropertyNames         *Schema                        `yaml:"propertyNames,omitempty"        json:"propertyNames,omitempty"`
+	ClosedKeys            bool                           `yaml:"closedKeys,omitempty"           json:"-"`
+	AllowedExtraKeys      []string                       `yaml:"allowedExtraKeys,omitempty"     json:"-"`
+	Closed                *bool                          `yaml:"closed,omitempty"               json:"-"`
+	EachItem              *Schema                        `yaml:"eachItem,omitempty"             json:"-"`
+	NoDefault             bool                           `yaml:"noDefault,omitempty"            json:"-"`
+	ConstFromValue        bool                           `yaml:"constFromValue,omitempty"       json:"-"`
+	IsSet                 bool                           `yaml:"set,omitempty"                  json:"-"`
+	RequiredProperties    []string                       `yaml:"requiredProperties,omitempty"   json:"-"`
+
+	// multiDocument marks a schema combining the schemas of multiple yaml documents with anyOf
+	multiDocument bool
 }
 
 func NewSchema(schemaType string) *Schema {
@@ -259,10 +465,43 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 	// copy all existing fields
 	*alias = schemaAlias(*s)
 
+	// items can be a list of schemas (tuple validation) and a schema can't be decoded into
+	// additionalItems, additionalProperties or unevaluatedProperties, so they're decoded separately
+	decodeNode, tupleItemsNode := withoutMappingKey(node, "items", yaml.SequenceNode)
+	decodeNode, additionalItemsNode := withoutMappingKey(decodeNode, "additionalItems", yaml.MappingNode)
+	decodeNode, additionalPropertiesNode := withoutMappingKey(decodeNode, "additionalProperties", yaml.MappingNode)
+	decodeNode, unevaluatedPropertiesNode := withoutMappingKey(decodeNode, "unevaluatedProperties", yaml.MappingNode)
+
 	// Unmarshal known fields into alias
-	if err := node.Decode(alias); err != nil {
+	if err := decodeNode.Decode(alias); err != nil {
 		return err
 	}
+	if tupleItemsNode != nil {
+		if err := tupleItemsNode.Decode(&alias.TupleItems); err != nil {
+			return err
+		}
+	}
+	if additionalItemsNode != nil {
+		var additionalItems Schema
+		if err := additionalItemsNode.Decode(&additionalItems); err != nil {
+			return err
+		}
+		alias.AdditionalItems = &additionalItems
+	}
+	if additionalPropertiesNode != nil {
+		var additionalProperties Schema
+		if err := additionalPropertiesNode.Decode(&additionalProperties); err != nil {
+			return err
+		}
+		alias.AdditionalProperties = &additionalProperties
+	}
+	if unevaluatedPropertiesNode != nil {
+		var unevaluatedProperties Schema
+		if err := unevaluatedPropertiesNode.Decode(&unevaluatedProperties); err != nil {
+			return err
+		}
+		alias.UnevaluatedProperties = &unevaluatedProperties
+	}
 
 	// Initialize CustomAnnotations map
 	alias.CustomAnnotations = make(map[string]interface{})
@@ -277,9 +516,13 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 		switch key {
 		case "additionalProperties", "default", "then", "patternProperties", "properties",
 			"if", "minimum", "multipleOf", "exclusiveMaximum", "items", "exclusiveMinimum",
-			"maximum", "else", "pattern", "const", "$ref", "$schema", "$id", "format",
-			"description", "title", "type", "anyOf", "allOf", "oneOf", "requiredProperties",
-			"examples", "enum", "deprecated", "required", "not", "dependencies":
+			"maximum", "else", "pattern", "const", "$ref", "$schema", "$id", "$defs", "$vocabulary", "format",
+			"description", "title", "$comment", "type", "anyOf", "allOf", "oneOf", "requiredProperties",
+			"examples", "enum", "deprecated", "required", "not", "dependencies", "propertyNames",
+			"unevaluatedProperties", "additionalItems", "closedKeys", "eachItem", "noDefault",
+			"contains", "minContains", "maxContains", "constFromValue", "minItems", "maxItems",
+			"uniqueItems", "minProperties", "maxProperties", "set",
+			"prefixItems", "tuple", "dependentRequired", "allowedExtraKeys", "closed":
 			// Skip known fields
 			continue
 		default:
@@ -295,52 +538,221 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 		}
 	}
 
+	// eachItem is a shorthand for items
+	if alias.EachItem != nil {
+		if alias.Items != nil || alias.TupleItems != nil {
+			return errors.New("cant use eachItem and items at the same time")
+		}
+		alias.Items = alias.EachItem
+		alias.EachItem = nil
+	}
+
+	// closed is a shorthand for additionalProperties: false (or true for open maps), which takes precedence over
+	// the generated additionalProperties
+	if alias.Closed != nil {
+		if alias.AdditionalProperties != nil {
+			return errors.New("cant use closed and additionalProperties at the same time")
+		}
+		allowed := !*alias.Closed
+		alias.AdditionalProperties = &allowed
+		alias.Closed = nil
+	}
+
+	// set is a shorthand for uniqueItems, IsSet is kept to sort the default if requested
+	if alias.IsSet {
+		alias.UniqueItems = true
+	}
+
+	// requiredProperties is an alias for the list of required properties
+	for _, name := range alias.RequiredProperties {
+		alias.Required.addAnnotated(name)
+	}
+	alias.RequiredProperties = nil
+
 	// Copy alias to the main struct
 	*s = Schema(*alias)
 	return nil
 }
 
+// sortSetItems sorts numbers numerically and strings lexically, other items are sorted by their json
+// representation. Numbers come before strings.
+func sortSetItems(items []interface{}) {
+	slices.SortStableFunc(items, func(a, b interface{}) int {
+		aNumber, aIsNumber := toFloat(a)
+		bNumber, bIsNumber := toFloat(b)
+		switch {
+		case aIsNumber && bIsNumber:
+			return cmp.Compare(aNumber, bNumber)
+		case aIsNumber:
+			return -1
+		case bIsNumber:
+			return 1
+		}
+		aString, aIsString := a.(string)
+		bString, bIsString := b.(string)
+		if !aIsString || !bIsString {
+			aJSON, _ := json.Marshal(a)
+			bJSON, _ := json.Marshal(b)
+			aString, bString = string(aJSON), string(bJSON)
+		}
+		return strings.Compare(aString, bString)
+	})
+}
+
+// toFloat converts the decoded yaml or json number to a float
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// withoutMappingKey returns a copy of the mapping node without the given key, if its value has the given kind.
+// The removed value is returned as well.
+func withoutMappingKey(node *yaml.Node, key string, kind yaml.Kind) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == kind {
+			stripped := *node
+			stripped.Content = append(append([]*yaml.Node{}, node.Content[:i]...), node.Content[i+2:]...)
+			return &stripped, node.Content[i+1]
+		}
+	}
+	return node, nil
+}
+
 // Set sets the HasData field to true
 func (s *Schema) Set() {
 	s.HasData = true
 }
 
-// DisableRequiredProperties sets disables all required fields
-func (s *Schema) DisableRequiredProperties() {
-	s.Required = NewBoolOrArrayOfString([]string{}, false)
+// subSchemas returns all the direct subschemas of the schema
+func (s *Schema) subSchemas() []*Schema {
+	var result []*Schema
 	for _, v := range s.Properties {
-		v.DisableRequiredProperties()
+		result = append(result, v)
 	}
-	if s.Items != nil {
-		s.Items.DisableRequiredProperties()
+	for _, v := range s.PatternProperties {
+		result = append(result, v)
 	}
-
-	if s.AnyOf != nil {
-		for _, v := range s.AnyOf {
-			v.DisableRequiredProperties()
+	for _, v := range s.Defs {
+		result = append(result, v)
+	}
+	for _, v := range s.Dependencies {
+		if v.Schema != nil {
+			result = append(result, v.Schema)
 		}
 	}
-	if s.OneOf != nil {
-		for _, v := range s.OneOf {
-			v.DisableRequiredProperties()
+	if subSchema, ok := s.AdditionalProperties.(*Schema); ok {
+		result = append(result, subSchema)
+	}
+	if subSchema, ok := s.UnevaluatedProperties.(*Schema); ok {
+		result = append(result, subSchema)
+	}
+	if subSchema, ok := s.AdditionalItems.(*Schema); ok {
+		result = append(result, subSchema)
+	}
+	result = append(result, s.TupleItems...)
+	result = append(result, s.PrefixItems...)
+	result = append(result, s.AnyOf...)
+	result = append(result, s.AllOf...)
+	result = append(result, s.OneOf...)
+	for _, v := range []*Schema{s.Items, s.If, s.Then, s.Else, s.Not, s.PropertyNames, s.Contains} {
+		if v != nil {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// enumToOneOf replaces the enum by a oneOf of consts, which are titled by the labels of the
+// x-enumDescriptions annotation. Enums without labels are kept.
+func (s *Schema) enumToOneOf() error {
+	annotation, ok := s.CustomAnnotations[EnumDescriptionsAnnotation]
+	if !ok || len(s.Enum) == 0 {
+		return nil
+	}
+	labels, ok := annotation.([]interface{})
+	if !ok || len(labels) != len(s.Enum) {
+		return fmt.Errorf("%s must be a list with a label per enum member", EnumDescriptionsAnnotation)
+	}
+	if len(s.OneOf) > 0 {
+		return fmt.Errorf("cant use %s and oneOf at the same time", EnumDescriptionsAnnotation)
+	}
+	for i, member := range s.Enum {
+		option := &Schema{Const: member, Title: fmt.Sprint(labels[i])}
+		// a nil const would be omitted
+		if member == nil {
+			option = &Schema{Type: StringOrArrayOfString{"null"}, Title: option.Title}
 		}
+		s.OneOf = append(s.OneOf, option)
 	}
-	if s.AllOf != nil {
-		for _, v := range s.AllOf {
-			v.DisableRequiredProperties()
+	s.Enum = nil
+	delete(s.CustomAnnotations, EnumDescriptionsAnnotation)
+	return nil
+}
+
+// addAllowedExtraKeys adds an empty schema for each of the allowed extra keys, which isn't a property yet.
+// So they're allowed even if additional properties aren't.
+func (s *Schema) addAllowedExtraKeys() {
+	for _, key := range s.AllowedExtraKeys {
+		if s.Properties == nil {
+			s.Properties = make(map[string]*Schema)
+		}
+		if _, ok := s.Properties[key]; !ok {
+			s.Properties[key] = &Schema{}
 		}
 	}
-	if s.If != nil {
-		s.If.DisableRequiredProperties()
+	s.AllowedExtraKeys = nil
+}
+
+// checkDefaultMatchesConst returns an error if the schema has a const and a different default.
+// They're compared by their json representation.
+func (s *Schema) checkDefaultMatchesConst() error {
+	if s.Const == nil || s.Default == nil {
+		return nil
+	}
+	constJSON, err := json.Marshal(s.Const)
+	if err != nil {
+		return err
+	}
+	defaultJSON, err := json.Marshal(s.Default)
+	if err != nil {
+		return err
 	}
-	if s.Else != nil {
-		s.Else.DisableRequiredProperties()
+	if !bytes.Equal(constJSON, defaultJSON) {
+		return fmt.Errorf("the default %s doesn't match the const %s", defaultJSON, constJSON)
 	}
-	if s.Then != nil {
-		s.Then.DisableRequiredProperties()
+	return nil
+}
+
+// scalarTypeUnion returns the distinct types of the given schemas, if they only define a scalar type
+func scalarTypeUnion(schemas []*Schema) (StringOrArrayOfString, bool) {
+	types := StringOrArrayOfString{}
+	for _, s := range schemas {
+		if len(s.Type) != 1 || s.Type.Matches("object") || s.Type.Matches("array") {
+			return nil, false
+		}
+		typeOnly := *s
+		typeOnly.Type = nil
+		typeOnly.Required = BoolOrArrayOfString{}
+		if !reflect.DeepEqual(typeOnly, Schema{}) {
+			return nil, false
+		}
+		if !slices.Contains(types, s.Type[0]) {
+			types = append(types, s.Type[0])
+		}
 	}
-	if s.Not != nil {
-		s.Not.DisableRequiredProperties()
+	return types, true
+}
+
+// DisableRequiredProperties sets disables all required fields
+func (s *Schema) DisableRequiredProperties() {
+	s.Required = NewBoolOrArrayOfString([]string{}, false)
+	for _, v := range s.subSchemas() {
+		v.DisableRequiredProperties()
 	}
 }
 
@@ -353,6 +765,29 @@ func (s Schema) ToJson() ([]byte, error) {
 	return res, nil
 }
 
+// ToJsonWithCustomAnnotations converts the data to raw json like ToJson, the custom annotations of the schema and
+// all its subschemas are emitted as configured by the output
+func (s Schema) ToJsonWithCustomAnnotations(output CustomAnnotationsOutput) ([]byte, error) {
+	if output == (CustomAnnotationsOutput{}) {
+		return s.ToJson()
+	}
+	data, err := json.Marshal(&s)
+	if err != nil {
+		return nil, err
+	}
+	// numbers are kept as they are written
+	schema := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, err
+	}
+	if err := forEachJSONSchema(schema, output.apply); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
 // Validate the schema
 func (s Schema) Validate() error {
 	jsonStr, err := s.ToJson()
@@ -360,10 +795,38 @@ func (s Schema) Validate() error {
 		return err
 	}
 
-	if _, err := jsonschema.CompileString("schema.json", string(jsonStr)); err != nil {
+	// Schemas without $schema are part of the generated draft-07 schema
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource("schema.json", bytes.NewReader(jsonStr)); err != nil {
+		return err
+	}
+	if _, err := compiler.Compile("schema.json"); err != nil {
 		return err
 	}
 
+	return s.validate()
+}
+
+// duplicateEnumMember returns the first member which equals a previous one as json, or an empty string. The
+// members are compared by their json representation, which is normalized (e.g. the keys of objects are sorted).
+func duplicateEnumMember(members []interface{}) (string, error) {
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		normalized, err := json.Marshal(member)
+		if err != nil {
+			return "", err
+		}
+		if seen[string(normalized)] {
+			return string(normalized), nil
+		}
+		seen[string(normalized)] = true
+	}
+	return "", nil
+}
+
+// validate checks the schema and all of its subschemas for unsupported combinations
+func (s *Schema) validate() error {
 	// Check if type is valid
 	if err := s.Type.Validate(); err != nil {
 		return err
@@ -384,50 +847,127 @@ func (s Schema) Validate() error {
 		return errors.New("cant use MinLength > MaxLength")
 	}
 
-	// Cant use Format and Pattern together
-	if s.Format != "" && s.Pattern != "" {
-		return errors.New("cant use format and pattern option at the same time")
+	// The keys of patternProperties must be valid regular expressions
+	for pattern := range s.PatternProperties {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("the patternProperties key %s is no valid regex: %v", pattern, err)
+		}
+	}
+
+	// additionalItems only applies to a list of items and was replaced by prefixItems in draft 2020-12
+	if s.AdditionalItems != nil {
+		if s.TupleItems == nil {
+			return errors.New("cant use additionalItems if items isn't a list of schemas")
+		}
+		if DraftFromSchemaURI(s.Schema) == Draft202012 {
+			return errors.New("cant use additionalItems in draft 2020-12, use prefixItems and items instead")
+		}
+	}
+
+	// If type and contains are used, type must be array
+	if s.Contains != nil && !s.Type.IsEmpty() && !s.Type.Matches("array") {
+		return fmt.Errorf("cant use contains if type is %s. Use type=array", s.Type)
 	}
 
-	// Validate nested Items schema
-	if s.Items != nil {
-		if err := s.Items.Validate(); err != nil {
+	// minContains and maxContains only count the items matching contains
+	if (s.MinContains != nil || s.MaxContains != nil) && s.Contains == nil {
+		return errors.New("cant use minContains or maxContains without contains")
+	}
+	if s.MinContains != nil && s.MaxContains != nil && *s.MinContains > *s.MaxContains {
+		return errors.New("cant use minContains > maxContains")
+	}
+
+	// $vocabulary is only allowed on the root of draft 2020-12 schemas
+	if s.Vocabulary != nil && DraftFromSchemaURI(s.Schema) != Draft202012 {
+		return errors.New("cant use $vocabulary if $schema isn't draft 2020-12")
+	}
+
+	// Validate nested schemas (items, properties, combinators...)
+	for _, subSchema := range s.subSchemas() {
+		if subSchema.Vocabulary != nil {
+			return errors.New("cant use $vocabulary on subschemas, only on the root schema")
+		}
+		if err := subSchema.validate(); err != nil {
 			return err
 		}
 	}
 
 	// If type and items are used, type must be array
-	if s.Items != nil && !s.Type.IsEmpty() && !s.Type.Matches("array") {
+	if (s.Items != nil || s.TupleItems != nil || s.PrefixItems != nil) && !s.Type.IsEmpty() && !s.Type.Matches("array") {
 		return fmt.Errorf("cant use items if type is %s. Use type=array", s.Type)
 	}
 
+	// If type and minItems or maxItems are used, type must be array
+	if (s.MinItems != nil || s.MaxItems != nil) && !s.Type.IsEmpty() && !s.Type.Matches("array") {
+		return fmt.Errorf("cant use minItems or maxItems if type is %s. Use type=array", s.Type)
+	}
+	if s.MinItems != nil && s.MaxItems != nil && *s.MinItems > *s.MaxItems {
+		return errors.New("cant use minItems > maxItems")
+	}
+
+	// If type and minProperties or maxProperties are used, type must be object
+	if (s.MinProperties != nil || s.MaxProperties != nil) && !s.Type.IsEmpty() && !s.Type.Matches("object") {
+		return fmt.Errorf("cant use minProperties or maxProperties if type is %s. Use type=object", s.Type)
+	}
+	if s.MinProperties != nil && s.MaxProperties != nil && *s.MinProperties > *s.MaxProperties {
+		return errors.New("cant use minProperties > maxProperties")
+	}
+
+	// If type and dependentRequired are used, type must be object
+	if s.DependentRequired != nil && !s.Type.IsEmpty() && !s.Type.Matches("object") {
+		return fmt.Errorf("cant use dependentRequired if type is %s. Use type=object", s.Type)
+	}
+	// If type and dependencies are used, type must be object
+	if s.Dependencies != nil && !s.Type.IsEmpty() && !s.Type.Matches("object") {
+		return fmt.Errorf("cant use dependencies if type is %s. Use type=object", s.Type)
+	}
+	for property, dependency := range s.Dependencies {
+		if dependency.Schema == nil && slices.Contains(dependency.Strings, "") {
+			return fmt.Errorf("the dependencies of %s contain an empty property name", property)
+		}
+	}
+	for property, dependencies := range s.DependentRequired {
+		if slices.Contains(dependencies, "") {
+			return fmt.Errorf("the dependentRequired of %s contains an empty property name", property)
+		}
+		if slices.Contains(dependencies, property) {
+			return fmt.Errorf("the dependentRequired of %s contains the property itself", property)
+		}
+		sorted := slices.Sorted(slices.Values(dependencies))
+		if len(slices.Compact(sorted)) != len(dependencies) {
+			return fmt.Errorf("the dependentRequired of %s contains duplicate property names", property)
+		}
+	}
+
+	// If type and allowedExtraKeys are used, type must be object
+	if s.AllowedExtraKeys != nil && !s.Type.IsEmpty() && !s.Type.Matches("object") {
+		return fmt.Errorf("cant use allowedExtraKeys if type is %s. Use type=object", s.Type)
+	}
+
+	// If type and uniqueItems are used, type must be array
+	if s.UniqueItems && !s.Type.IsEmpty() && !s.Type.Matches("array") {
+		return fmt.Errorf("cant use uniqueItems if type is %s. Use type=array", s.Type)
+	}
+
+	// Check if the enum members are unique, objects which only differ in the order of their keys are equal
+	duplicate, err := duplicateEnumMember(s.Enum)
+	if err != nil {
+		return err
+	}
+	if duplicate != "" {
+		return fmt.Errorf("the enum member %s is defined multiple times", duplicate)
+	}
+
 	if s.Const != nil && !s.Type.IsEmpty() {
 		return errors.New("if your are using const, you can't use type")
 	}
 
-	// Check if format is valid
-	// https://json-schema.org/understanding-json-schema/reference/string.html#built-in-formats
-	// We currently dont support https://datatracker.ietf.org/doc/html/rfc3339#appendix-A
-	if s.Format != "" &&
-		s.Format != "date-time" &&
-		s.Format != "time" &&
-		s.Format != "date" &&
-		s.Format != "duration" &&
-		s.Format != "email" &&
-		s.Format != "idn-email" &&
-		s.Format != "hostname" &&
-		s.Format != "idn-hostname" &&
-		s.Format != "ipv4" &&
-		s.Format != "ipv6" &&
-		s.Format != "uuid" &&
-		s.Format != "uri" &&
-		s.Format != "uri-reference" &&
-		s.Format != "iri" &&
-		s.Format != "iri-reference" &&
-		s.Format != "uri-template" &&
-		s.Format != "json-pointer" &&
-		s.Format != "relative-json-pointer" &&
-		s.Format != "regex" {
+	if err := s.checkDefaultMatchesConst(); err != nil {
+		return err
+	}
+
+	// Check if format is valid, custom formats can be added with RegisterFormat
+	if s.Format != "" && !isKnownFormat(s.Format) {
 		return fmt.Errorf("the format %s is not supported", s.Format)
 	}
 
@@ -458,10 +998,12 @@ func (s Schema) Validate() error {
 	return nil
 }
 
-var possibleSkipFields = []string{"title", "description", "required", "default", "additionalProperties"}
+var possibleSkipFields = []string{"title", "description", "required", "default", "additionalProperties", "global"}
 
 type SkipAutoGenerationConfig struct {
 	Title, Description, Required, Default, AdditionalProperties bool
+	// Global skips the global property, which helm needs on the root of charts
+	Global bool
 }
 
 func NewSkipAutoGenerationConfig(flag []string) (*SkipAutoGenerationConfig, error) {
@@ -488,6 +1030,9 @@ func NewSkipAutoGenerationConfig(flag []string) (*SkipAutoGenerationConfig, erro
 		if fieldName == "additionalProperties" {
 			config.AdditionalProperties = true
 		}
+		if fieldName == "global" {
+			config.Global = true
+		}
 	}
 
 	if len(invalidFlags) != 0 {
@@ -497,7 +1042,9 @@ func NewSkipAutoGenerationConfig(flag []string) (*SkipAutoGenerationConfig, erro
 	return &config, nil
 }
 
-func typeFromTag(tag string) ([]string, error) {
+// TypeFromTag returns the jsonschema type used for values with the given yaml tag (e.g. !!int is
+// an integer). Timestamps are strings, unsupported tags return an error.
+func TypeFromTag(tag string) ([]string, error) {
 	switch tag {
 	case nullTag:
 		return []string{"null"}, nil
@@ -519,75 +1066,78 @@ func typeFromTag(tag string) ([]string, error) {
 	return []string{}, fmt.Errorf("unsupported yaml tag found: %s", tag)
 }
 
-// FixRequiredProperties iterates over the properties and checks if required has a boolean value.
-// Then the property is added to the parents required property list
-func FixRequiredProperties(schema *Schema) error {
-
-	if schema.Properties != nil {
-		for propName, propValue := range schema.Properties {
-			FixRequiredProperties(propValue)
-			if propValue.Required.Bool && !slices.Contains(schema.Required.Strings, propName) {
-				schema.Required.Strings = append(schema.Required.Strings, propName)
-			}
+// inferType returns the type of the given node and optionally additional constraints.
+// The TypeInferer is consulted first, if it doesn't return a type, the handler of the yaml tag is used. Without
+// a handler, the type is derived from the yaml tag.
+func (o *GenerateOptions) inferType(node *yaml.Node) (StringOrArrayOfString, *Schema, error) {
+	if o.TypeInferer != nil {
+		nodeType, constraints, err := o.TypeInferer(node)
+		if err != nil {
+			return nil, nil, err
 		}
-		if !slices.Contains(schema.Type, "object") {
-			// If .Properties is set, type must be object
-			schema.Type = []string{"object"}
+		if len(nodeType) > 0 {
+			if len(constraints) == 0 {
+				return nodeType, nil, nil
+			}
+			constraintsYaml, err := yaml.Marshal(constraints)
+			if err != nil {
+				return nil, nil, err
+			}
+			var constraintsSchema Schema
+			if err := yaml.Unmarshal(constraintsYaml, &constraintsSchema); err != nil {
+				return nil, nil, err
+			}
+			return nodeType, &constraintsSchema, nil
 		}
 	}
 
-	if schema.Then != nil {
-		FixRequiredProperties(schema.Then)
-	}
-
-	if schema.If != nil {
-		FixRequiredProperties(schema.If)
-	}
-
-	if schema.Else != nil {
-		FixRequiredProperties(schema.Else)
+	if handler, ok := o.TagHandlers[node.Tag]; ok {
+		tagSchema, err := handler(node)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tagSchema != nil && len(tagSchema.Type) > 0 {
+			return tagSchema.Type, tagSchema, nil
+		}
 	}
 
-	if schema.Items != nil {
-		FixRequiredProperties(schema.Items)
-	}
+	nodeType, err := TypeFromTag(node.Tag)
+	return nodeType, nil, err
+}
 
-	if schema.AdditionalProperties != nil {
-		if subSchema, ok := schema.AdditionalProperties.(Schema); ok {
-			FixRequiredProperties(&subSchema)
-		}
-	}
+// FixRequiredProperties iterates over the properties and checks if required has a boolean value.
+// Then the property is added to the parents required property list
+func FixRequiredProperties(schema *Schema) error {
+	return fixRequiredProperties(schema, false)
+}
 
-	if len(schema.AnyOf) > 0 {
-		for _, subSchema := range schema.AnyOf {
-			FixRequiredProperties(subSchema)
-		}
+// fixRequiredProperties works like FixRequiredProperties, readOnly properties are never added to the required
+// properties if readOnlyNotRequired is set
+func fixRequiredProperties(schema *Schema, readOnlyNotRequired bool) error {
+	for _, subSchema := range schema.subSchemas() {
+		fixRequiredProperties(subSchema, readOnlyNotRequired)
 	}
 
-	if len(schema.AllOf) > 0 {
-		for _, subSchema := range schema.AllOf {
-			FixRequiredProperties(subSchema)
+	if schema.Properties != nil {
+		for propName, propValue := range schema.Properties {
+			if propValue.Required.Bool && !(readOnlyNotRequired && propValue.ReadOnly) {
+				schema.Required.addAnnotated(propName)
+			}
 		}
-	}
-
-	if len(schema.OneOf) > 0 {
-		for _, subSchema := range schema.OneOf {
-			FixRequiredProperties(subSchema)
+		if !slices.Contains(schema.Type, "object") {
+			// If .Properties is set, type must be object
+			schema.Type = []string{"object"}
 		}
 	}
 
-	if schema.Not != nil {
-		FixRequiredProperties(schema.Not)
-	}
-
-	// If we're specifying the required properties in a condition, don't populate Required on this schema
+	// If we're specifying the required properties in a condition, don't populate the inferred Required on this schema
 	if (schema.Then != nil && len(schema.Then.Required.Strings) > 0) || (schema.Else != nil && len(schema.Else.Required.Strings) > 0) {
-		schema.Required.Strings = []string{}
+		schema.Required.resetToAnnotated()
 	}
 	if len(schema.OneOf) > 0 {
 		for _, one := range schema.OneOf {
 			if len(one.Required.Strings) > 0 {
-				schema.Required.Strings = []string{}
+				schema.Required.resetToAnnotated()
 				break
 			}
 		}
@@ -595,7 +1145,7 @@ func FixRequiredProperties(schema *Schema) error {
 	if len(schema.AllOf) > 0 {
 		for _, all := range schema.AllOf {
 			if len(all.Required.Strings) > 0 {
-				schema.Required.Strings = []string{}
+				schema.Required.resetToAnnotated()
 				break
 			}
 		}
@@ -603,7 +1153,7 @@ func FixRequiredProperties(schema *Schema) error {
 	if len(schema.AnyOf) > 0 {
 		for _, any := range schema.AnyOf {
 			if len(any.Required.Strings) > 0 {
-				schema.Required.Strings = []string{}
+				schema.Required.resetToAnnotated()
 				break
 			}
 		}
@@ -612,43 +1162,69 @@ func FixRequiredProperties(schema *Schema) error {
 	return nil
 }
 
-// GetSchemaFromComment parses the annotations from the given comment
+// GetSchemaFromComment parses the annotations from the given comment. If the comment contains
+// multiple @schema blocks, the later ones patch (deep-merge over) the earlier ones.
 func GetSchemaFromComment(comment string) (Schema, string, error) {
 	var result Schema
-	scanner := bufio.NewScanner(strings.NewReader(comment))
+	scanner := util.NewLineScanner(strings.NewReader(comment))
 	description := []string{}
-	rawSchema := []string{}
+	rawSchemas := [][]string{}
 	insideSchemaBlock := false
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		if strings.HasPrefix(line, SchemaPrefix) {
 			insideSchemaBlock = !insideSchemaBlock
+			if insideSchemaBlock {
+				rawSchemas = append(rawSchemas, []string{})
+			}
 			continue
 		}
 		if insideSchemaBlock {
 			content := strings.TrimPrefix(line, CommentPrefix)
-			rawSchema = append(rawSchema, strings.TrimPrefix(strings.TrimPrefix(content, CommentPrefix), " "))
+			rawSchemas[len(rawSchemas)-1] = append(rawSchemas[len(rawSchemas)-1], strings.TrimPrefix(strings.TrimPrefix(content, CommentPrefix), " "))
 			result.Set()
 		} else {
 			description = append(description, strings.TrimPrefix(strings.TrimPrefix(line, CommentPrefix), " "))
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return result, "", err
+	}
 
 	if insideSchemaBlock {
 		return result, "",
 			fmt.Errorf("unclosed schema block found in comment: %s", comment)
 	}
 
-	err := yaml.Unmarshal([]byte(strings.Join(rawSchema, "\n")), &result)
-	if err != nil {
-		return result, "", err
+	for i, rawSchema := range rawSchemas {
+		// yaml.Unmarshal would silently ignore all documents but the first one
+		var blockSchema Schema
+		decoder := yaml.NewDecoder(strings.NewReader(strings.Join(rawSchema, "\n")))
+		if err := decoder.Decode(&blockSchema); err != nil && err != io.EOF {
+			return result, "", err
+		}
+		var next yaml.Node
+		if err := decoder.Decode(&next); err != io.EOF {
+			return result, "",
+				fmt.Errorf("multiple yaml documents (separated by ---) found in schema block, use a single document: %s", comment)
+		}
+
+		if i == 0 {
+			blockSchema.HasData = result.HasData
+			result = blockSchema
+		} else {
+			patchSchema(&result, &blockSchema)
+		}
 	}
 
 	return result, strings.Join(description, "\n"), nil
 }
 
-// YamlToSchema recursevly parses the given yaml.Node and creates a jsonschema from it
+// YamlToSchema recursevly parses the given yaml.Node and creates a jsonschema from it.
+// Errors in the values are fatal.
+//
+// Deprecated: Use Generate, which supports all GenerateOptions and returns the errors.
 func YamlToSchema(
 	valuesPath string,
 	node *yaml.Node,
@@ -658,25 +1234,87 @@ func YamlToSchema(
 	parentRequiredProperties *[]string,
 	parentId string,
 ) *Schema {
+	opts := &GenerateOptions{
+		ValuesPath:               valuesPath,
+		KeepFullComment:          keepFullComment,
+		DontRemoveHelmDocsPrefix: dontRemoveHelmDocsPrefix,
+		SkipAutoGeneration:       skipAutoGeneration,
+	}
+	schema, err := yamlToSchema(node, opts, parentRequiredProperties, parentId)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return schema
+}
+
+// yamlToSchema recursevly parses the given yaml.Node and creates a jsonschema from it
+func yamlToSchema(node *yaml.Node, opts *GenerateOptions, parentRequiredProperties *[]string, parentId string) (*Schema, error) {
+	valuesPath := opts.valuesPath()
+	skipAutoGeneration := opts.skipAutoGeneration()
 	schema := NewSchema("object")
+	// Empty values (or values which only contain comments) are parsed into an empty node
+	if node.Kind == 0 {
+		node = &yaml.Node{Kind: yaml.DocumentNode}
+	}
 	switch node.Kind {
 	case yaml.DocumentNode:
-		if len(node.Content) != 1 {
-			log.Fatalf("Strange yaml document found:\n%v\n", node.Content[:])
-		}
-
-		schema.Schema = "http://json-schema.org/draft-07/schema#"
-		schema.Properties = YamlToSchema(
-			valuesPath,
-			node.Content[0],
-			keepFullComment,
-			dontRemoveHelmDocsPrefix,
-			skipAutoGeneration,
-			&schema.Required.Strings,
-			"",
-		).Properties
-
-		if _, ok := schema.Properties["global"]; !ok {
+		// the options collect state (like hoisted $defs) during the generation, so they're copied per document
+		documentOpts := *opts
+		opts = &documentOpts
+		resolved, err := resolveAliases(node)
+		if err != nil {
+			return nil, fmt.Errorf("error while resolving the aliases of the document: %v", err)
+		}
+		node = resolved
+
+		if len(node.Content) > 1 {
+			return nil, fmt.Errorf("strange yaml document found: %v", node.Content[:])
+		}
+
+		// The root schema can be annotated with a @schema block at the top of the document
+		rootSchema, _, err := GetSchemaFromComment(node.HeadComment)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing comment of the document: %v", err)
+		}
+		if rootSchema.HasData {
+			if err := rootSchema.Validate(); err != nil {
+				return nil, fmt.Errorf("error while validating jsonschema of the document: %v", err)
+			}
+			if len(rootSchema.Type) == 0 {
+				rootSchema.Type = []string{"object"}
+			}
+			schema = &rootSchema
+		}
+
+		if schema.Schema == "" {
+			schema.Schema = opts.schemaURI()
+		}
+		// the dialect of the document decides which keywords are generated (e.g. prefixItems)
+		opts.SchemaURI = schema.Schema
+		if opts.UnevaluatedProperties && DraftFromSchemaURI(opts.SchemaURI) < Draft201909 {
+			opts.warnf(
+				"unevaluatedProperties requires draft 2019-09 or newer, but the schema uses %s. Using additionalProperties instead",
+				opts.SchemaURI,
+			)
+			opts.UnevaluatedProperties = false
+		}
+		if schema.Id == "" {
+			schema.Id = opts.BaseURI
+		}
+		if schema.Properties == nil && len(node.Content) == 1 {
+			documentSchema, err := yamlToSchema(node.Content[0], opts, &schema.Required.Strings, schema.Id)
+			if err != nil {
+				return nil, err
+			}
+			schema.Properties = documentSchema.Properties
+		}
+		schema.addAllowedExtraKeys()
+		if rootSchema.HasData {
+			// like for annotated keys, conditionals (e.g. then.required) replace the inferred required keys
+			fixRequiredProperties(schema, opts.ReadOnlyNotRequired)
+		}
+
+		if _, ok := schema.Properties["global"]; !ok && !skipAutoGeneration.Global {
 			// global key must be present, otherwise helm lint will fail
 			if schema.Properties == nil {
 				schema.Properties = make(map[string]*Schema)
@@ -692,9 +1330,19 @@ func YamlToSchema(
 			}
 		}
 
-		// always disable on top level
-		if !skipAutoGeneration.AdditionalProperties {
-			schema.AdditionalProperties = new(bool)
+		// always disable on top level (unless annotated)
+		if !skipAutoGeneration.AdditionalProperties && schema.AdditionalProperties == nil {
+			opts.disallowAdditionalProperties(schema)
+		}
+
+		// $defs of referenced files
+		for name, def := range opts.hoistedDefs {
+			if schema.Defs == nil {
+				schema.Defs = make(map[string]*Schema)
+			}
+			if _, ok := schema.Defs[name]; !ok {
+				schema.Defs[name] = def
+			}
 		}
 	case yaml.MappingNode:
 		for i := 0; i < len(node.Content); i += 2 {
@@ -702,62 +1350,64 @@ func YamlToSchema(
 			valueNode := node.Content[i+1]
 
 			comment := keyNode.HeadComment
-			if !keepFullComment {
+			if !opts.KeepFullComment {
 				leadingCommentsRemover := regexp.MustCompile(`(?s)(?m)(?:.*\n{2,})+`)
 				comment = leadingCommentsRemover.ReplaceAllString(comment, "")
 			}
 
 			keyNodeSchema, description, err := GetSchemaFromComment(comment)
 			if err != nil {
-				log.Fatalf("Error while parsing comment of key %s: %v", keyNode.Value, err)
+				return nil, fmt.Errorf("error while parsing comment of key %s: %v", keyNode.Value, err)
 			}
-			if !dontRemoveHelmDocsPrefix {
+			if !opts.DontRemoveHelmDocsPrefix {
 				// remove all lines containing helm-docs @tags, like @ignored, or one of those:
 				// https://github.com/norwoodj/helm-docs/blob/v1.14.2/pkg/helm/chart_info.go#L18-L24
 				helmDocsTagsRemover := regexp.MustCompile(`(?ms)(\r\n|\r|\n)?\s*@\w+(\s+--\s)?[^\n\r]*`)
 				description = helmDocsTagsRemover.ReplaceAllString(description, "")
 
 				prefixRemover := regexp.MustCompile(`(?m)^--\s?`)
+				isHelmDocsComment := prefixRemover.MatchString(description)
 				description = prefixRemover.ReplaceAllString(description, "")
+
+				if opts.HelmDocsTitle && isHelmDocsComment && keyNodeSchema.Title == "" {
+					keyNodeSchema.Title, description = splitHelmDocsTitle(description)
+				}
+			}
+			if !opts.KeepFullComment {
+				description = normalizeDescription(description)
+			}
+			if opts.FootComments {
+				if footComment := footComment(keyNode); footComment != "" && description != "" {
+					description += "\n" + footComment
+				} else if footComment != "" {
+					description = footComment
+				}
+			}
+
+			// the resolved id is the base of the ids of the nested keys, which are written relative to it
+			id, err := resolveId(parentId, keyNodeSchema.Id)
+			if err != nil {
+				return nil, fmt.Errorf("error while resolving the $id of key %s: %v", keyNode.Value, err)
+			}
+			if opts.BaseURI != "" && keyNodeSchema.Id != "" && parentId != "" {
+				keyNodeSchema.Id = relativeId(parentId, id)
 			}
 
 			if keyNodeSchema.Ref != "" {
 				// Check if Ref is a relative file to the values file
 				refParts := strings.Split(keyNodeSchema.Ref, "#")
-				var byteValue []byte
-				if schemaPath, err := util.IsRelativeFile(valuesPath, refParts[0]); err == nil {
-					file, err := os.Open(schemaPath)
-					if err == nil {
-						byteValue, _ = io.ReadAll(file)
-					} else {
-						log.Fatalln(err)
+				if _, err := util.IsRelativeFile(valuesPath, refParts[0]); err == nil {
+					obj, err := opts.loadRef(valuesPath, valuesPath, keyNodeSchema.Ref, nil)
+					if err != nil {
+						return nil, err
 					}
-
-					if len(byteValue) > 0 {
+					if obj != nil {
 						var relSchema Schema
-						if len(refParts) > 1 {
-							// Found json-pointer
-							var obj interface{}
-							json.Unmarshal(byteValue, &obj)
-							jsonPointerResultRaw, err := jsonpointer.Get(obj, refParts[1])
-							if err != nil {
-								log.Fatal(err)
-							}
-							jsonPointerResultMarshaled, err := json.Marshal(jsonPointerResultRaw)
-							if err != nil {
-								log.Fatal(err)
-							}
-							err = json.Unmarshal(jsonPointerResultMarshaled, &relSchema)
-							if err != nil {
-								log.Fatal(err)
-							}
-						} else {
-							// No json-pointer
-							err = json.Unmarshal(byteValue, &relSchema)
-							if err != nil {
-								log.Fatal(err)
-							}
+						if err := fromJSONValue(obj, &relSchema); err != nil {
+							return nil, err
 						}
+						// the $defs are hoisted already
+						relSchema.Defs = nil
 						keyNodeSchema = relSchema
 						keyNodeSchema.HasData = true
 					}
@@ -767,34 +1417,53 @@ func YamlToSchema(
 
 			}
 
+			typeInferred := len(keyNodeSchema.Type) == 0
 			if keyNodeSchema.HasData {
-				// set the type if not explicitly set
-				if len(keyNodeSchema.Type) == 0 {
-					nodeType, err := typeFromTag(valueNode.Tag)
+				// set the type if not explicitly set, a const defines the type itself
+				if typeInferred && keyNodeSchema.Const == nil {
+					nodeType, constraints, err := opts.inferType(valueNode)
 					if err != nil {
-						log.Fatal(err)
+						return nil, err
 					}
 					keyNodeSchema.Type = nodeType
+					// annotated values take precedence
+					mergeSchema(&keyNodeSchema, constraints, false)
 				}
-				if err := keyNodeSchema.Validate(); err != nil {
-					log.Fatalf(
-						"Error while validating jsonschema of key %s: %v",
+				if err := opts.withHoistedDefs(keyNodeSchema).Validate(); err != nil {
+					return nil, fmt.Errorf(
+						"error while validating jsonschema of key %s: %v",
 						keyNode.Value,
 						err,
 					)
 				}
+				if keyNodeSchema.Vocabulary != nil {
+					return nil, fmt.Errorf("error while validating jsonschema of key %s: $vocabulary can only be used on the root schema", keyNode.Value)
+				}
 			} else {
-				nodeType, err := typeFromTag(valueNode.Tag)
+				nodeType, constraints, err := opts.inferType(valueNode)
 				if err != nil {
-					log.Fatal(err)
+					return nil, err
 				}
 				keyNodeSchema.Type = nodeType
+				mergeSchema(&keyNodeSchema, constraints, false)
+			}
+
+			// Timestamps are typed as string, but we can add the matching format
+			if opts.Inference.TimestampFormats && valueNode.Tag == timestampTag && keyNodeSchema.Type.Matches("string") &&
+				keyNodeSchema.Format == "" && keyNodeSchema.Pattern == "" {
+				keyNodeSchema.Format = timestampFormat(valueNode.Value)
+			}
+
+			// Strings which look like e.g. an url or email get the matching format
+			if opts.Inference.StringFormats && valueNode.Tag == strTag && keyNodeSchema.Type.Matches("string") &&
+				keyNodeSchema.Format == "" && keyNodeSchema.Pattern == "" {
+				keyNodeSchema.Format = stringFormat(valueNode.Value)
 			}
 
 			// Try to get type from examples, if they are set
 			if len(keyNodeSchema.Examples) > 0 && len(keyNodeSchema.Type) == 0 {
 				type Examples struct {
-					Examples []string `yaml:"examples"`
+					Examples []interface{} `yaml:"examples"`
 				}
 				examplesNode := &yaml.Node{}
 				examplesContent := &Examples{Examples: keyNodeSchema.Examples}
@@ -802,15 +1471,10 @@ func YamlToSchema(
 				if err == nil {
 					err := yaml.Unmarshal(examplesArray, examplesNode)
 					if err == nil {
-						ex := YamlToSchema(
-							valuesPath,
-							examplesNode.Content[0],
-							keepFullComment,
-							dontRemoveHelmDocsPrefix,
-							skipAutoGeneration,
-							&[]string{},
-							keyNodeSchema.Id,
-						)
+						ex, err := yamlToSchema(examplesNode.Content[0], opts, &[]string{}, id)
+						if err != nil {
+							return nil, err
+						}
 						examples := ex.Properties["examples"]
 						if examples != nil && examples.Items != nil {
 							keyNodeSchema.Type = examples.Items.Type
@@ -821,21 +1485,77 @@ func YamlToSchema(
 			}
 
 			// Treat null case
-			// Don't explicity set the type for null, as it doesn't make sense to declare fields which can only be null (should be any instead)
-			if len(keyNodeSchema.Type) == 1 && keyNodeSchema.Type[0] == "null" {
+			// Unless the null handling is strict, don't explicity set the type for null, as it doesn't make sense
+			// to declare fields which can only be null (should be any instead)
+			if len(keyNodeSchema.Type) == 1 && keyNodeSchema.Type[0] == "null" && opts.Inference.NullHandling != NullStrict {
+				if opts.StrictTypes && typeInferred && keyNodeSchema.Ref == "" && len(keyNodeSchema.Enum) == 0 &&
+					keyNodeSchema.Const == nil && len(keyNodeSchema.AnyOf) == 0 && len(keyNodeSchema.OneOf) == 0 &&
+					len(keyNodeSchema.AllOf) == 0 {
+					opts.warnf(
+						"The type of key %s (line %d) can't be inferred from its empty value, please annotate its type",
+						keyNode.Value,
+						keyNode.Line,
+					)
+				}
 				keyNodeSchema.Type = nil
 			}
+			// The null value of a key with an annotated type must stay valid
+			if opts.Inference.NullHandling == NullPermissive && valueNode.Tag == nullTag && !typeInferred &&
+				len(keyNodeSchema.Type) > 0 && !keyNodeSchema.Type.Matches("null") {
+				keyNodeSchema.Type = append(keyNodeSchema.Type, "null")
+			}
+
+			// Huge enums bloat the schema and slow down editors
+			if opts.MaxEnumSize > 0 && len(keyNodeSchema.Enum) > opts.MaxEnumSize {
+				if opts.EnumOverflow == EnumOverflowDrop {
+					log.Debugf("Dropping the enum of key %s with %d members", keyNode.Value, len(keyNodeSchema.Enum))
+					keyNodeSchema.Enum = nil
+				} else {
+					opts.warnf(
+						"The enum of key %s has %d members, which is more than the maximum of %d",
+						keyNode.Value,
+						len(keyNodeSchema.Enum),
+						opts.MaxEnumSize,
+					)
+				}
+			}
 
 			// Don't set type if Enum is set
 			if len(keyNodeSchema.Enum) > 0 {
 				keyNodeSchema.Type = nil
 			}
 
+			// Labeled enums can be emitted as oneOf, so every member gets a title
+			if opts.EnumTitles {
+				if err := keyNodeSchema.enumToOneOf(); err != nil {
+					return nil, fmt.Errorf("error while validating jsonschema of key %s: %v", keyNode.Value, err)
+				}
+			}
+
+			// Examples written as strings can be coerced to the type of non-string values
+			if opts.CoerceExamples && len(keyNodeSchema.Type) > 0 && !keyNodeSchema.Type.Matches("string") {
+				for i, example := range keyNodeSchema.Examples {
+					if example, ok := example.(string); ok {
+						keyNodeSchema.Examples[i] = castNodeValueByType(
+							&yaml.Node{Kind: yaml.ScalarNode, Value: example},
+							keyNodeSchema.Type,
+						)
+					}
+				}
+			}
+
 			// only validate or default if $ref is not set
 			if keyNodeSchema.Ref == "" {
 
+				// Read-only keys aren't set by the user, so they shouldn't be required
+				readOnlyNotRequired := opts.ReadOnlyNotRequired && keyNodeSchema.ReadOnly
+				if readOnlyNotRequired {
+					keyNodeSchema.Required.Bool = false
+				}
+
 				// Add key to required array of parent
-				if keyNodeSchema.Required.Bool || (len(keyNodeSchema.Required.Strings) == 0 && !skipAutoGeneration.Required && !keyNodeSchema.HasData) {
+				inferRequired := !opts.Inference.RequiredIfNonEmpty || !isEmptyValue(valueNode)
+				if keyNodeSchema.Required.Bool || (len(keyNodeSchema.Required.Strings) == 0 && !skipAutoGeneration.Required && !keyNodeSchema.HasData && !readOnlyNotRequired && inferRequired) {
 					if !slices.Contains(*parentRequiredProperties, keyNode.Value) {
 						*parentRequiredProperties = append(*parentRequiredProperties, keyNode.Value)
 					}
@@ -843,12 +1563,12 @@ func YamlToSchema(
 
 				if !skipAutoGeneration.AdditionalProperties && valueNode.Kind == yaml.MappingNode &&
 					(!keyNodeSchema.HasData || keyNodeSchema.AdditionalProperties == nil) {
-					keyNodeSchema.AdditionalProperties = new(bool)
+					opts.disallowAdditionalProperties(&keyNodeSchema)
 				}
 
 				// If no title was set, use the key value
 				if keyNodeSchema.Title == "" && !skipAutoGeneration.Title {
-					keyNodeSchema.Title = keyNode.Value
+					keyNodeSchema.Title = opts.Inference.title(keyNode.Value)
 				}
 
 				// If no description was set, use the rest of the comment as description
@@ -856,56 +1576,125 @@ func YamlToSchema(
 					keyNodeSchema.Description = description
 				}
 
-				// If no default value was set, use the values node value as default
-				if !skipAutoGeneration.Default && keyNodeSchema.Default == nil && valueNode.Kind == yaml.ScalarNode {
-					keyNodeSchema.Default = castNodeValueByType(valueNode.Value, keyNodeSchema.Type)
+				// Sensitive values can be set, but their value isn't published as default
+				if opts.Inference.isSecret(keyNode.Value, &keyNodeSchema) {
+					keyNodeSchema.WriteOnly = true
+					keyNodeSchema.NoDefault = true
+				}
+
+				// The value can be locked by using it as const instead of default.
+				// If no default value was set, use the values node value as default.
+				// Null values (empty, ~ or null) have no default.
+				if keyNodeSchema.ConstFromValue {
+					if keyNodeSchema.Const == nil && valueNode.Kind == yaml.ScalarNode && valueNode.Tag != nullTag {
+						keyNodeSchema.Const = castNodeValueByType(valueNode, keyNodeSchema.Type)
+						// const can't be used together with type
+						keyNodeSchema.Type = nil
+					}
+				} else if !skipAutoGeneration.Default && !keyNodeSchema.NoDefault && keyNodeSchema.Default == nil && valueNode.Kind == yaml.ScalarNode &&
+					valueNode.Tag != nullTag {
+					if opts.PlaceholderHandling != PlaceholderKeep && valueNode.Tag == strTag &&
+						opts.placeholderPattern().MatchString(valueNode.Value) {
+						// Placeholders are substituted later on, so they're no useful default
+						if opts.PlaceholderHandling == PlaceholderAddPattern && keyNodeSchema.Pattern == "" && keyNodeSchema.Format == "" {
+							keyNodeSchema.Pattern = opts.placeholderPattern().String()
+						}
+					} else {
+						// keys with an enum or const have no type, the default keeps the type of the value
+						defaultType := keyNodeSchema.Type
+						if len(defaultType) == 0 {
+							defaultType, _ = TypeFromTag(valueNode.Tag)
+						}
+						keyNodeSchema.Default = castNodeValueByType(valueNode, defaultType)
+					}
+				} else if opts.ZeroDefaults && !skipAutoGeneration.Default && !keyNodeSchema.NoDefault &&
+					keyNodeSchema.Default == nil && valueNode.Tag == nullTag && !typeInferred {
+					keyNodeSchema.Default = zeroValue(keyNodeSchema.Type)
 				}
 
+				if items, ok := keyNodeSchema.Default.([]interface{}); ok && opts.SortSetDefaults && keyNodeSchema.IsSet {
+					sortSetItems(items)
+				}
+
+				if err := keyNodeSchema.checkDefaultMatchesConst(); err != nil {
+					return nil, fmt.Errorf("error while validating jsonschema of key %s: %v", keyNode.Value, err)
+				}
+
+				opts.addKubernetesNameConstraints(keyNode.Value, valueNode, &keyNodeSchema)
+
 				// If the value is another map and no properties are set, get them from default values
 				if valueNode.Kind == yaml.MappingNode && keyNodeSchema.Properties == nil {
-					keyNodeSchema.Properties = YamlToSchema(
-						valuesPath,
-						valueNode,
-						keepFullComment,
-						dontRemoveHelmDocsPrefix,
-						skipAutoGeneration,
-						&keyNodeSchema.Required.Strings,
-						keyNodeSchema.Id,
-					).Properties
-					FixRequiredProperties(&keyNodeSchema)
-				} else if valueNode.Kind == yaml.SequenceNode && keyNodeSchema.Items == nil {
+					mapSchema, err := yamlToSchema(valueNode, opts, &keyNodeSchema.Required.Strings, id)
+					if err != nil {
+						return nil, err
+					}
+					keyNodeSchema.Properties = mapSchema.Properties
+					fixRequiredProperties(&keyNodeSchema, opts.ReadOnlyNotRequired)
+				} else if valueNode.Kind == yaml.SequenceNode && keyNodeSchema.Items == nil && keyNodeSchema.TupleItems == nil &&
+					keyNodeSchema.PrefixItems == nil {
 					// If the value is a sequence, but no items are predefined
 					seqSchema := NewSchema("")
 					for _, itemNode := range valueNode.Content {
 						if itemNode.Kind == yaml.ScalarNode {
-							itemNodeType, err := typeFromTag(itemNode.Tag)
+							itemNodeType, constraints, err := opts.inferType(itemNode)
 							if err != nil {
-								log.Fatal(err)
+								return nil, err
 							}
-							seqSchema.AnyOf = append(seqSchema.AnyOf, NewSchema(itemNodeType[0]))
+							itemSchema := NewSchema(itemNodeType[0])
+							itemSchema.Type = itemNodeType
+							mergeSchema(itemSchema, constraints, false)
+							seqSchema.AnyOf = append(seqSchema.AnyOf, itemSchema)
 						} else {
 							itemRequiredProperties := []string{}
-							itemSchema := YamlToSchema(valuesPath, itemNode, keepFullComment, dontRemoveHelmDocsPrefix, skipAutoGeneration, &itemRequiredProperties, keyNodeSchema.Id)
+							itemSchema, err := yamlToSchema(itemNode, opts, &itemRequiredProperties, id)
+							if err != nil {
+								return nil, err
+							}
 
 							for _, req := range itemRequiredProperties {
 								itemSchema.Required.Strings = append(itemSchema.Required.Strings, req)
 							}
 
 							if !skipAutoGeneration.AdditionalProperties && itemNode.Kind == yaml.MappingNode && (!itemSchema.HasData || itemSchema.AdditionalProperties == nil) {
-								itemSchema.AdditionalProperties = new(bool)
+								opts.disallowAdditionalProperties(itemSchema)
 							}
 
 							seqSchema.AnyOf = append(seqSchema.AnyOf, itemSchema)
 						}
 					}
-					if len(seqSchema.AnyOf) == 1 {
-						seqSchema = seqSchema.AnyOf[0]
+					if keyNodeSchema.Tuple {
+						// Every item has its own schema, draft 2020-12 uses prefixItems for that
+						if DraftFromSchemaURI(opts.schemaURI()) == Draft202012 {
+							keyNodeSchema.PrefixItems = seqSchema.AnyOf
+						} else {
+							keyNodeSchema.TupleItems = seqSchema.AnyOf
+						}
+					} else {
+						if len(seqSchema.AnyOf) == 1 {
+							seqSchema = seqSchema.AnyOf[0]
+						} else if types, ok := scalarTypeUnion(seqSchema.AnyOf); ok {
+							// Items which only differ in their scalar type are written as a type array
+							seqSchema = NewSchema("")
+							seqSchema.Type = types
+						}
+						keyNodeSchema.Items = seqSchema
 					}
-					keyNodeSchema.Items = seqSchema
 					keyNodeSchema.Type = []string{"array"}
 					// Because the `required` field isn't valid jsonschema (but just a helper boolean)
 					// we must convert them to valid requiredProperties fields
-					FixRequiredProperties(&keyNodeSchema)
+					fixRequiredProperties(&keyNodeSchema, opts.ReadOnlyNotRequired)
+				} else if valueNode.Kind == yaml.SequenceNode && opts.CheckItemsConsistency && keyNodeSchema.Items != nil {
+					// The items are annotated, warn if the values don't match them
+					opts.checkItemsConsistency(keyNode.Value, valueNode, keyNodeSchema.Items)
+				}
+
+				keyNodeSchema.addAllowedExtraKeys()
+
+				// Only allow the known keys of the map as property names
+				if keyNodeSchema.ClosedKeys && keyNodeSchema.PropertyNames == nil {
+					keyNodeSchema.PropertyNames = &Schema{
+						Enum: propertyKeys(valueNode, keyNodeSchema.Properties),
+					}
 				}
 			}
 
@@ -915,23 +1704,169 @@ func YamlToSchema(
 			schema.Properties[keyNode.Value] = &keyNodeSchema
 		}
 	}
-	return schema
+	return schema, nil
+}
+
+// checkItemsConsistency warns about sequence items whose type doesn't match the type of the annotated items schema
+func (o *GenerateOptions) checkItemsConsistency(key string, sequenceNode *yaml.Node, items *Schema) {
+	if items.Type.IsEmpty() {
+		return
+	}
+	for i, itemNode := range sequenceNode.Content {
+		itemType, err := TypeFromTag(itemNode.Tag)
+		if err != nil {
+			o.warnf("Could not determine the type of item %d of key %s: %v", i, key, err)
+			continue
+		}
+		// integers are numbers as well
+		if !items.Type.Matches(itemType[0]) && !(itemType[0] == "integer" && items.Type.Matches("number")) {
+			o.warnf(
+				"Item %d of key %s has type %s, which doesn't match the annotated items type %s",
+				i,
+				key,
+				itemType[0],
+				items.Type,
+			)
+		}
+	}
+}
+
+// timestampFormat returns the jsonschema format matching the given yaml timestamp.
+// YAML also allows timestamps which aren't valid RFC3339 (e.g. "2001-12-14 21:59:43.10 -5"),
+// for those no format is returned.
+func timestampFormat(value string) string {
+	if _, err := time.Parse(time.DateOnly, value); err == nil {
+		return "date"
+	}
+	if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return "date-time"
+	}
+	return ""
+}
+
+// stringFormat returns the jsonschema format the given string value looks like (uri, email, ipv4 or date).
+// Relative references and other ambiguous values get no format.
+func stringFormat(value string) string {
+	if ip := net.ParseIP(value); ip != nil && ip.To4() != nil && !strings.Contains(value, ":") {
+		return "ipv4"
+	}
+	if _, err := time.Parse(time.DateOnly, value); err == nil {
+		return "date"
+	}
+	if address, err := mail.ParseAddress(value); err == nil && address.Address == value {
+		return "email"
+	}
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		return "uri"
+	}
+	return ""
+}
+
+// propertyKeys returns the keys of the given mapping node in source order,
+// followed by the sorted keys of the properties which aren't part of the node
+func propertyKeys(node *yaml.Node, properties map[string]*Schema) []interface{} {
+	keys := []string{}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keys = append(keys, node.Content[i].Value)
+		}
+	}
+
+	additionalKeys := []string{}
+	for key := range properties {
+		if !slices.Contains(keys, key) {
+			additionalKeys = append(additionalKeys, key)
+		}
+	}
+	slices.Sort(additionalKeys)
+
+	enum := []interface{}{}
+	for _, key := range append(keys, additionalKeys...) {
+		enum = append(enum, key)
+	}
+	return enum
+}
+
+// footComment returns the comment following the value of the key. The parser attaches it to the key at the
+// indentation of the comment, so a comment following a nested map documents its last key, unless it's indented
+// like the key of the nested map.
+func footComment(keyNode *yaml.Node) string {
+	if keyNode.FootComment == "" {
+		return ""
+	}
+	lines := strings.Split(keyNode.FootComment, "\n")
+	for j, line := range lines {
+		lines[j] = strings.TrimPrefix(strings.TrimPrefix(line, CommentPrefix), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var blankLinesMatcher = regexp.MustCompile(`\n{3,}`)
+
+// normalizeDescription removes trailing whitespace of all lines and collapses multiple blank lines into one
+func normalizeDescription(description string) string {
+	lines := strings.Split(description, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	description = strings.Join(lines, "\n")
+	return blankLinesMatcher.ReplaceAllString(description, "\n\n")
+}
+
+// splitHelmDocsTitle splits a helm-docs description into a title (its first sentence or line) and the
+// remaining description. The optional type hint of helm-docs, e.g. (string), isn't part of the title.
+func splitHelmDocsTitle(description string) (string, string) {
+	description = strings.TrimSpace(regexp.MustCompile(`^\s*\(\w+\)`).ReplaceAllString(description, ""))
+	end := len(description)
+	rest := end
+	if i := strings.Index(description, "\n"); i >= 0 {
+		end, rest = i, i+1
+	}
+	if match := regexp.MustCompile(`\.(\s|$)`).FindStringIndex(description); match != nil && match[0] < end {
+		end, rest = match[0], match[1]
+	}
+	return strings.TrimSpace(description[:end]), strings.TrimSpace(description[rest:])
 }
 
-func castNodeValueByType(rawValue string, fieldType StringOrArrayOfString) any {
+// zeroValue returns the zero value of the first non-null type
+func zeroValue(fieldType StringOrArrayOfString) any {
+	for _, t := range fieldType {
+		switch t {
+		case "string":
+			return ""
+		case "integer", "number":
+			return 0
+		case "boolean":
+			return false
+		case "array":
+			return []interface{}{}
+		case "object":
+			return map[string]interface{}{}
+		}
+	}
+	return nil
+}
+
+func castNodeValueByType(node *yaml.Node, fieldType StringOrArrayOfString) any {
+	rawValue := node.Value
 	if len(fieldType) == 0 {
 		return rawValue
 	}
 
+	// quoted strings (e.g. "80") stay strings, if the type union allows them. Unquoted yes, no, on and off
+	// are still booleans, like for helm.
+	if node.Tag == strTag && node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 && fieldType.Matches("string") {
+		return rawValue
+	}
+
 	// rawValue must be one of fielTypes
 	for _, t := range fieldType {
 		switch t {
 		case "boolean":
-			switch rawValue {
-			case "true":
-				return true
-			case "false":
-				return false
+			// decoding into a bool also accepts the yaml 1.1 literals (yes, no, on, off, ...)
+			var v bool
+			if err := node.Decode(&v); err == nil {
+				return v
 			}
 		case "integer":
 			v, err := strconv.Atoi(rawValue)
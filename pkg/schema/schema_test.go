@@ -1,13 +1,38 @@
 package schema
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/magiconair/properties/assert"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"gopkg.in/yaml.v3"
 )
 
+// generateTestSchema generates the schema of the given values, like they were read from a values.yaml
+func generateTestSchema(t *testing.T, data string, opts *GenerateOptions) *Schema {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+	generateOpts := *opts
+	if generateOpts.ValuesPath == "" {
+		generateOpts.ValuesPath = "values.yaml"
+	}
+	s, err := yamlToSchema(&node, &generateOpts, nil, "")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	return s
+}
+
 func TestValidate(t *testing.T) {
 	tests := []struct {
 		comment       string
@@ -72,10 +97,10 @@ func TestValidate(t *testing.T) {
 		{
 			comment: `
 # @schema
-# pattern: ^foo
-# format: ipv4
+# pattern: "@example\\.org$"
+# format: email
 # @schema`,
-			expectedValid: false,
+			expectedValid: true,
 		},
 		{
 			comment: `
@@ -147,6 +172,20 @@ func TestValidate(t *testing.T) {
 # @schema`,
 			expectedValid: true,
 		},
+		{
+			comment: `
+# @schema
+# enum: [foo, bar]
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# enum: [foo, bar, foo]
+# @schema`,
+			expectedValid: false,
+		},
 	}
 
 	for _, test := range tests {
@@ -172,6 +211,37 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestDuplicateEnumMembers(t *testing.T) {
+	var members []interface{}
+	if err := yaml.Unmarshal([]byte("[{a: 1, b: 2}, {b: 2, a: 1}]"), &members); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+	// the order of the keys doesn't matter
+	duplicate, err := duplicateEnumMember(members)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, duplicate, `{"a":1,"b":2}`)
+
+	duplicate, err = duplicateEnumMember([]interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, duplicate, "")
+}
+
+func TestYamlToSchema(t *testing.T) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("# -- the name\nname: foo\n"), &node); err != nil {
+		t.Fatalf("Error while reading test data: %v", err)
+	}
+	// the deprecated signature is kept for the users of the package
+	s := YamlToSchema("values.yaml", &node, false, true, &SkipAutoGenerationConfig{Title: true}, nil, "")
+	assert.Equal(t, s.Properties["name"].Title, "")
+	assert.Equal(t, s.Properties["name"].Description, "-- the name")
+	assert.Equal(t, s.Properties["name"].Default, "foo")
+}
+
 func TestUnmarshalYAML(t *testing.T) {
 	yamlData := `
 type: string
@@ -186,3 +256,2410 @@ x-custom-foo: bar
 	assert.Equal(t, schema.Type, StringOrArrayOfString{"string"})
 	assert.Equal(t, schema.CustomAnnotations["x-custom-foo"], "bar")
 }
+
+func TestMarshalCustomAnnotations(t *testing.T) {
+	tests := []struct {
+		output        CustomAnnotationsOutput
+		annotations   map[string]interface{}
+		expected      string
+		expectedError string
+	}{
+		{
+			output:      CustomAnnotationsOutput{},
+			annotations: map[string]interface{}{"x-foo-bar": "baz"},
+			expected:    `{"properties":{"foo":{"required":[],"type":"string","x-foo-bar":"baz"}},"required":[],"type":"object"}`,
+		},
+		{
+			output:      CustomAnnotationsOutput{CamelCase: true},
+			annotations: map[string]interface{}{"x-foo-bar": "baz"},
+			expected:    `{"properties":{"foo":{"required":[],"type":"string","x-fooBar":"baz"}},"required":[],"type":"object"}`,
+		},
+		{
+			output:      CustomAnnotationsOutput{Nested: true},
+			annotations: map[string]interface{}{"x-foo-bar": "baz"},
+			expected:    `{"properties":{"foo":{"required":[],"type":"string","x-meta":{"x-foo-bar":"baz"}}},"required":[],"type":"object"}`,
+		},
+		{
+			output:      CustomAnnotationsOutput{Nested: true, CamelCase: true},
+			annotations: map[string]interface{}{"x-foo-bar": "baz"},
+			expected:    `{"properties":{"foo":{"required":[],"type":"string","x-meta":{"x-fooBar":"baz"}}},"required":[],"type":"object"}`,
+		},
+		{
+			output:        CustomAnnotationsOutput{CamelCase: true},
+			annotations:   map[string]interface{}{"x-foo-bar": "baz", "x-fooBar": "qux"},
+			expectedError: "the custom annotations x-foo-bar and x-fooBar are both emitted as x-fooBar",
+		},
+		{
+			// the keys only collide in camelCase
+			output:      CustomAnnotationsOutput{Nested: true},
+			annotations: map[string]interface{}{"x-foo-bar": "baz", "x-fooBar": "qux"},
+			expected:    `{"properties":{"foo":{"required":[],"type":"string","x-meta":{"x-foo-bar":"baz","x-fooBar":"qux"}}},"required":[],"type":"object"}`,
+		},
+	}
+
+	for _, test := range tests {
+		s := &Schema{
+			Type: StringOrArrayOfString{"object"},
+			Properties: map[string]*Schema{
+				"foo": {
+					Type:              StringOrArrayOfString{"string"},
+					CustomAnnotations: test.annotations,
+				},
+			},
+		}
+		data, err := s.ToJsonWithCustomAnnotations(test.output)
+		if test.expectedError != "" {
+			if err == nil || err.Error() != test.expectedError {
+				t.Errorf("Expected the error %q, but got: %v", test.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, data); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, compacted.String(), test.expected)
+	}
+}
+
+func TestClosedKeys(t *testing.T) {
+	data := `
+# @schema
+# closedKeys: true
+# @schema
+resources:
+  requests: {}
+  limits: {}
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	resources := s.Properties["resources"]
+	if resources.PropertyNames == nil {
+		t.Fatalf("Expected propertyNames to be set")
+	}
+	assert.Equal(t, resources.PropertyNames.Enum, []interface{}{"requests", "limits"})
+}
+
+func TestComment(t *testing.T) {
+	s, _, err := GetSchemaFromComment("# @schema\n# $comment: internal note\n# @schema")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Comment, "internal note")
+	assert.Equal(t, s.CustomAnnotations, map[string]interface{}{})
+
+	data, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, strings.Contains(string(data), `"$comment": "internal note"`), true)
+
+	var decoded Schema
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, decoded.Comment, "internal note")
+}
+
+func TestClosed(t *testing.T) {
+	data := `
+# @schema
+# closed: false
+# @schema
+podAnnotations:
+  foo: bar
+# @schema
+# closed: true
+# @schema
+image:
+  tag: latest
+service:
+  port: 80
+`
+	allowed, disallowed := true, false
+	tests := []struct {
+		opts                      *GenerateOptions
+		expectedServiceAdditional interface{}
+	}{
+		{opts: &GenerateOptions{}, expectedServiceAdditional: new(bool)},
+		{opts: &GenerateOptions{SkipAutoGeneration: &SkipAutoGenerationConfig{AdditionalProperties: true}}, expectedServiceAdditional: nil},
+	}
+
+	for _, test := range tests {
+		// closed overrides the generated additionalProperties
+		s := generateTestSchema(t, data, test.opts)
+		assert.Equal(t, s.Properties["podAnnotations"].AdditionalProperties, &allowed)
+		assert.Equal(t, s.Properties["image"].AdditionalProperties, &disallowed)
+		assert.Equal(t, s.Properties["service"].AdditionalProperties, test.expectedServiceAdditional)
+	}
+
+	if _, _, err := GetSchemaFromComment("# @schema\n# closed: true\n# additionalProperties: false\n# @schema"); err == nil {
+		t.Error("Expected an error for closed and additionalProperties at the same time")
+	}
+}
+
+func TestAllowedExtraKeys(t *testing.T) {
+	data := `
+# @schema
+# allowedExtraKeys: [nodeSelector, tolerations]
+# @schema
+scheduling:
+  affinity: {}
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	scheduling := s.Properties["scheduling"]
+	assert.Equal(t, scheduling.AdditionalProperties, new(bool))
+	assert.Equal(t, scheduling.Properties["affinity"].Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, scheduling.Properties["nodeSelector"], &Schema{})
+	assert.Equal(t, scheduling.Properties["tolerations"], &Schema{})
+	// the extra keys are allowed, not required
+	assert.Equal(t, scheduling.Required.Strings, []string{"affinity"})
+
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(string(output))); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	instances := []struct {
+		values        string
+		expectedValid bool
+	}{
+		{values: `{"scheduling": {"affinity": {}, "nodeSelector": {"zone": "a"}}}`, expectedValid: true},
+		{values: `{"scheduling": {"affinity": {}, "tolerations": []}}`, expectedValid: true},
+		{values: `{"scheduling": {"affinity": {}, "priority": 1}}`, expectedValid: false},
+	}
+	for _, instance := range instances {
+		var values interface{}
+		if err := json.Unmarshal([]byte(instance.values), &values); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		err := compiled.Validate(values)
+		if valid := err == nil; valid != instance.expectedValid {
+			t.Errorf("Expected the values %s to be valid=%t, but it's %t (%v)", instance.values, instance.expectedValid, valid, err)
+		}
+	}
+}
+
+func TestFixRequiredPropertiesKeepsAnnotated(t *testing.T) {
+	comment := `
+# @schema
+# required: [foo]
+# oneOf:
+#   - required: [bar]
+#   - required: [baz]
+# @schema`
+	s, _, err := GetSchemaFromComment(comment)
+	if err != nil {
+		t.Fatalf("Error while parsing comment: %v", err)
+	}
+	// simulate an inferred required property
+	s.Required.Strings = append(s.Required.Strings, "inferred")
+
+	if err := FixRequiredProperties(&s); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Required.Strings, []string{"foo"})
+}
+
+func TestRequiredPropertiesOfSubSchemas(t *testing.T) {
+	tests := []struct {
+		comment string
+		// subSchema returns the subschema with the property foo
+		subSchema func(s *Schema) *Schema
+	}{
+		{comment: "# contains:\n#   properties:\n#     foo:\n#       required: true", subSchema: func(s *Schema) *Schema { return s.Contains }},
+		{comment: "# patternProperties:\n#   ^x-:\n#     properties:\n#       foo:\n#         required: true", subSchema: func(s *Schema) *Schema { return s.PatternProperties["^x-"] }},
+		{comment: "# propertyNames:\n#   properties:\n#     foo:\n#       required: true", subSchema: func(s *Schema) *Schema { return s.PropertyNames }},
+		{comment: "# unevaluatedProperties:\n#   properties:\n#     foo:\n#       required: true", subSchema: func(s *Schema) *Schema { return s.UnevaluatedProperties.(*Schema) }},
+		{comment: "# additionalItems:\n#   properties:\n#     foo:\n#       required: true", subSchema: func(s *Schema) *Schema { return s.AdditionalItems.(*Schema) }},
+	}
+
+	for _, test := range tests {
+		s, _, err := GetSchemaFromComment("# @schema\n" + test.comment + "\n# @schema")
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		if err := FixRequiredProperties(&s); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, test.subSchema(&s).Required.Strings, []string{"foo"})
+
+		s.DisableRequiredProperties()
+		assert.Equal(t, test.subSchema(&s).Required.Strings, []string{})
+		assert.Equal(t, test.subSchema(&s).Properties["foo"].Required.Bool, false)
+	}
+}
+
+func TestPlaceholderHandling(t *testing.T) {
+	data := `
+foo: ${FOO}
+bar: baz
+`
+	tests := []struct {
+		handling        PlaceholderHandling
+		expectedDefault interface{}
+		expectedPattern string
+	}{
+		{
+			handling:        PlaceholderKeep,
+			expectedDefault: "${FOO}",
+		},
+		{
+			handling: PlaceholderOmitDefault,
+		},
+		{
+			handling:        PlaceholderAddPattern,
+			expectedPattern: DefaultPlaceholderPattern,
+		},
+	}
+
+	for _, test := range tests {
+		opts := &GenerateOptions{PlaceholderHandling: test.handling}
+		s := generateTestSchema(t, data, opts)
+
+		assert.Equal(t, s.Properties["foo"].Default, test.expectedDefault)
+		assert.Equal(t, s.Properties["foo"].Pattern, test.expectedPattern)
+		assert.Equal(t, s.Properties["bar"].Default, "baz")
+	}
+}
+
+func TestValidateSubSchemas(t *testing.T) {
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# patternProperties:
+#   "^foo":
+#     type: string
+#     minLength: 2
+#     maxLength: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# propertyNames:
+#   format: doesnotexist
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# if:
+#   properties:
+#     enabled:
+#       const: true
+# then:
+#   properties:
+#     host:
+#       format: doesnotexist
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# if:
+#   properties:
+#     enabled:
+#       const: true
+# else:
+#   not:
+#     minLength: 2
+#     maxLength: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# if:
+#   properties:
+#     enabled:
+#       const: true
+# then:
+#   properties:
+#     host:
+#       format: hostname
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# allOf:
+#   - type: boolean
+#     minimum: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# patternProperties:
+#   "^foo":
+#     type: string
+#     minLength: 1
+#     maxLength: 2
+# @schema`,
+			expectedValid: true,
+		},
+	}
+
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		valid := err == nil
+		if valid != test.expectedValid {
+			t.Errorf(
+				"Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)",
+				test.comment,
+				test.expectedValid,
+				valid,
+				err,
+			)
+		}
+	}
+}
+
+func TestEachItem(t *testing.T) {
+	comment := `
+# @schema
+# type: array
+# eachItem:
+#   type: string
+#   minLength: 1
+# @schema`
+	s, _, err := GetSchemaFromComment(comment)
+	if err != nil {
+		t.Fatalf("Error while parsing comment: %v", err)
+	}
+	if s.Items == nil {
+		t.Fatalf("Expected eachItem to be expanded into items")
+	}
+	assert.Equal(t, s.Items.Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, *s.Items.MinLength, 1)
+	if s.EachItem != nil {
+		t.Errorf("Expected eachItem to be cleared after the expansion")
+	}
+
+	comment = `
+# @schema
+# items:
+#   type: string
+# eachItem:
+#   type: string
+# @schema`
+	if _, _, err := GetSchemaFromComment(comment); err == nil {
+		t.Errorf("Expected an error when using items and eachItem together")
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	data := `
+# @schema
+# if:
+#   properties:
+#     mode:
+#       const: prod
+# then:
+#   required: [replicas]
+# @schema
+deployment:
+  mode: dev
+  replicas: 1
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	deployment := s.Properties["deployment"]
+	assert.Equal(t, deployment.Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, deployment.If.Properties["mode"].Const, "prod")
+	assert.Equal(t, deployment.Then.Required.Strings, []string{"replicas"})
+	// the condition decides which keys are required
+	assert.Equal(t, deployment.Required.Strings, []string{})
+	assert.Equal(t, deployment.Properties["replicas"].Default, 1)
+
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(string(output))); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	instances := []struct {
+		values        string
+		expectedValid bool
+	}{
+		{values: `{"deployment": {"mode": "dev"}}`, expectedValid: true},
+		{values: `{"deployment": {"mode": "prod", "replicas": 3}}`, expectedValid: true},
+		{values: `{"deployment": {"mode": "prod"}}`, expectedValid: false},
+	}
+	for _, instance := range instances {
+		var values interface{}
+		if err := json.Unmarshal([]byte(instance.values), &values); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		err := compiled.Validate(values)
+		if valid := err == nil; valid != instance.expectedValid {
+			t.Errorf("Expected the values %s to be valid=%t, but it's %t (%v)", instance.values, instance.expectedValid, valid, err)
+		}
+	}
+
+	// conditionals in the root annotation behave the same
+	data = `# @schema
+# if:
+#   properties:
+#     mode:
+#       const: prod
+# then:
+#   required: [replicas]
+# @schema
+
+mode: dev
+replicas: 1
+`
+	s = generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, s.Then.Required.Strings, []string{"replicas"})
+	assert.Equal(t, s.Required.Strings, []string{})
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+}
+
+func TestAdditionalPropertiesSchema(t *testing.T) {
+	data := `
+# @schema
+# additionalProperties:
+#   type: string
+# @schema
+labels:
+  app: foo
+# @schema
+# additionalProperties:
+#   properties:
+#     image:
+#       type: string
+#       required: true
+# @schema
+sidecars: {}
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	labels, ok := s.Properties["labels"].AdditionalProperties.(*Schema)
+	if !ok {
+		t.Fatalf("Expected additionalProperties to be a schema, but got %T", s.Properties["labels"].AdditionalProperties)
+	}
+	assert.Equal(t, labels.Type, StringOrArrayOfString{"string"})
+
+	sidecars, ok := s.Properties["sidecars"].AdditionalProperties.(*Schema)
+	if !ok {
+		t.Fatalf("Expected additionalProperties to be a schema, but got %T", s.Properties["sidecars"].AdditionalProperties)
+	}
+	assert.Equal(t, sidecars.Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, sidecars.Required.Strings, []string{"image"})
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(string(output))); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	instances := []struct {
+		values        string
+		expectedValid bool
+	}{
+		{values: `{"labels": {"app": "foo", "team": "bar"}}`, expectedValid: true},
+		{values: `{"labels": {"replicas": 1}}`, expectedValid: false},
+		{values: `{"sidecars": {"proxy": {"image": "envoy"}}}`, expectedValid: true},
+		{values: `{"sidecars": {"proxy": {}}}`, expectedValid: false},
+	}
+	for _, instance := range instances {
+		var values interface{}
+		if err := json.Unmarshal([]byte(instance.values), &values); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		err := compiled.Validate(values)
+		if valid := err == nil; valid != instance.expectedValid {
+			t.Errorf("Expected the values %s to be valid=%t, but it's %t (%v)", instance.values, instance.expectedValid, valid, err)
+		}
+	}
+}
+
+func TestFootComments(t *testing.T) {
+	data := `
+# The image
+image: nginx
+# Pulled from docker hub
+
+resources:
+  limits: {}
+  # Requests default to the limits
+# Set both for the guaranteed QoS class
+
+replicas: 1
+# Scaled by the autoscaler
+`
+	tests := []struct {
+		opts                *GenerateOptions
+		expectedDescription map[string]string
+	}{
+		{
+			opts:                &GenerateOptions{},
+			expectedDescription: map[string]string{"image": "The image", "resources": "", "limits": "", "replicas": ""},
+		},
+		{
+			opts: &GenerateOptions{FootComments: true},
+			expectedDescription: map[string]string{
+				"image": "The image\nPulled from docker hub",
+				// the indentation of the comment decides whether it belongs to the nested map or its last key
+				"resources": "Set both for the guaranteed QoS class",
+				"limits":    "Requests default to the limits",
+				"replicas":  "Scaled by the autoscaler",
+			},
+		},
+		{
+			opts:                &GenerateOptions{FootComments: true, SkipAutoGeneration: &SkipAutoGenerationConfig{Description: true}},
+			expectedDescription: map[string]string{"image": "", "resources": "", "limits": "", "replicas": ""},
+		},
+	}
+
+	for _, test := range tests {
+		s := generateTestSchema(t, data, test.opts)
+		assert.Equal(t, s.Properties["image"].Description, test.expectedDescription["image"])
+		assert.Equal(t, s.Properties["resources"].Description, test.expectedDescription["resources"])
+		assert.Equal(t, s.Properties["resources"].Properties["limits"].Description, test.expectedDescription["limits"])
+		assert.Equal(t, s.Properties["replicas"].Description, test.expectedDescription["replicas"])
+	}
+}
+
+func TestTimestampFormats(t *testing.T) {
+	data := `
+date: 2024-01-31
+dateTime: 2024-01-31T12:30:00Z
+spaced: 2001-12-14 21:59:43.10 -5
+`
+	tests := []struct {
+		enabled  bool
+		expected map[string]string
+	}{
+		{
+			enabled:  false,
+			expected: map[string]string{"date": "", "dateTime": "", "spaced": ""},
+		},
+		{
+			enabled:  true,
+			expected: map[string]string{"date": "date", "dateTime": "date-time", "spaced": ""},
+		},
+	}
+
+	for _, test := range tests {
+		opts := &GenerateOptions{Inference: InferenceOptions{TimestampFormats: test.enabled}}
+		s := generateTestSchema(t, data, opts)
+		for key, format := range test.expected {
+			assert.Equal(t, s.Properties[key].Type, StringOrArrayOfString{"string"})
+			assert.Equal(t, s.Properties[key].Format, format)
+		}
+	}
+}
+
+func TestStringFormats(t *testing.T) {
+	data := `
+url: https://example.com/path
+email: admin@example.com
+ip: 10.0.0.1
+ipv6: "::1"
+date: "2024-01-31"
+name: foo
+path: ./relative/path
+# @schema
+# format: hostname
+# @schema
+host: https://example.com
+`
+	tests := []struct {
+		enabled  bool
+		expected map[string]string
+	}{
+		{
+			enabled:  false,
+			expected: map[string]string{"url": "", "email": "", "ip": "", "date": "", "name": "", "path": "", "host": "hostname"},
+		},
+		{
+			enabled: true,
+			expected: map[string]string{
+				"url": "uri", "email": "email", "ip": "ipv4", "ipv6": "", "date": "date", "name": "", "path": "", "host": "hostname",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		opts := &GenerateOptions{Inference: InferenceOptions{StringFormats: test.enabled}}
+		s := generateTestSchema(t, data, opts)
+		for key, format := range test.expected {
+			assert.Equal(t, s.Properties[key].Format, format, key)
+		}
+	}
+}
+
+func TestRootAnnotation(t *testing.T) {
+	data := `# @schema
+# title: My chart
+# additionalProperties: true
+# @schema
+
+foo: bar
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Title, "My chart")
+	assert.Equal(t, s.AdditionalProperties, true)
+	assert.Equal(t, s.Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, s.Schema, "http://json-schema.org/draft-07/schema#")
+	assert.Equal(t, s.Required.Strings, []string{"foo"})
+	if _, ok := s.Properties["global"]; !ok {
+		t.Errorf("Expected the global property to be injected")
+	}
+}
+
+func TestCheckItemsConsistency(t *testing.T) {
+	data := `
+# @schema
+# items:
+#   type: string
+# @schema
+hosts:
+  - foo
+  - 1
+`
+	tests := []struct {
+		enabled          bool
+		expectedWarnings int
+	}{
+		{enabled: false, expectedWarnings: 0},
+		{enabled: true, expectedWarnings: 1},
+	}
+
+	hook := logtest.NewGlobal()
+	for _, test := range tests {
+		opts := &GenerateOptions{CheckItemsConsistency: test.enabled}
+		s := generateTestSchema(t, data, opts)
+
+		assert.Equal(t, s.Properties["hosts"].Items.Type, StringOrArrayOfString{"string"})
+		assert.Equal(t, len(hook.AllEntries()), test.expectedWarnings)
+		hook.Reset()
+	}
+}
+
+func TestVocabulary(t *testing.T) {
+	data := `# @schema
+# $schema: https://json-schema.org/draft/2020-12/schema
+# $vocabulary:
+#   https://json-schema.org/draft/2020-12/vocab/core: true
+#   https://example.org/vocab/custom: false
+# @schema
+
+foo: bar
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	jsonStr, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(jsonStr, &result); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result["$vocabulary"], map[string]interface{}{
+		"https://json-schema.org/draft/2020-12/vocab/core": true,
+		"https://example.org/vocab/custom":                 false,
+	})
+
+	// draft-07 doesn't know $vocabulary
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	if err := s.Validate(); err == nil {
+		t.Errorf("Expected $vocabulary to be rejected for draft-07")
+	}
+
+	// only allowed on the root schema
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.Properties["foo"].Vocabulary = map[string]bool{"https://example.org/vocab/custom": true}
+	if err := s.Validate(); err == nil {
+		t.Errorf("Expected $vocabulary to be rejected on subschemas")
+	}
+}
+
+func TestMinLengthZero(t *testing.T) {
+	data := `
+# @schema
+# minLength: 0
+# @schema
+optional: ""
+implicit: ""
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	jsonStr, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var result struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(jsonStr, &result); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	minLength, ok := result.Properties["optional"]["minLength"]
+	if !ok {
+		t.Fatalf("Expected an explicit minLength of 0 to be emitted")
+	}
+	assert.Equal(t, minLength, float64(0))
+
+	// minLength must never be generated
+	if _, ok := result.Properties["implicit"]["minLength"]; ok {
+		t.Errorf("Expected no minLength to be generated")
+	}
+}
+
+func TestTypeInferer(t *testing.T) {
+	data := `
+memory: 128Mi
+name: foo
+sizes:
+  - 1Gi
+# @schema
+# pattern: ^[0-9]+Gi$
+# @schema
+storage: 10Gi
+`
+	quantityPattern := `^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti)$`
+	quantityMatcher := regexp.MustCompile(quantityPattern)
+	opts := &GenerateOptions{
+		TypeInferer: func(node *yaml.Node) (StringOrArrayOfString, map[string]interface{}, error) {
+			if node.Tag == "!!str" && quantityMatcher.MatchString(node.Value) {
+				return StringOrArrayOfString{"string"}, map[string]interface{}{"pattern": quantityPattern}, nil
+			}
+			return nil, nil, nil
+		},
+	}
+
+	s := generateTestSchema(t, data, opts)
+
+	assert.Equal(t, s.Properties["memory"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, s.Properties["memory"].Pattern, quantityPattern)
+	assert.Equal(t, s.Properties["name"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, s.Properties["name"].Pattern, "")
+	assert.Equal(t, s.Properties["sizes"].Items.Pattern, quantityPattern)
+	// annotations take precedence
+	assert.Equal(t, s.Properties["storage"].Pattern, "^[0-9]+Gi$")
+}
+
+func TestTagHandlers(t *testing.T) {
+	data := `
+password: !secret changeme
+# @schema
+# minLength: 8
+# @schema
+token: !secret abcdefgh
+user: admin
+`
+	opts := &GenerateOptions{
+		TagHandlers: map[string]TagHandler{
+			"!secret": func(node *yaml.Node) (*Schema, error) {
+				return &Schema{Type: StringOrArrayOfString{"string"}, WriteOnly: true}, nil
+			},
+		},
+	}
+
+	s := generateTestSchema(t, data, opts)
+
+	assert.Equal(t, s.Properties["password"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, s.Properties["password"].WriteOnly, true)
+	assert.Equal(t, s.Properties["token"].WriteOnly, true)
+	assert.Equal(t, *s.Properties["token"].MinLength, 8)
+	assert.Equal(t, s.Properties["user"].WriteOnly, false)
+}
+
+func TestTypeUnionDefault(t *testing.T) {
+	data := `
+# @schema
+# type: [string, integer]
+# @schema
+port: 80
+# @schema
+# type: [string, integer]
+# @schema
+targetPort: "80"
+# @schema
+# type: [string, integer]
+# @schema
+namedPort: http
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Properties["port"].Type, StringOrArrayOfString{"string", "integer"})
+	assert.Equal(t, s.Properties["port"].Default, 80)
+	assert.Equal(t, s.Properties["targetPort"].Default, "80")
+	assert.Equal(t, s.Properties["namedPort"].Default, "http")
+
+	output, err := s.Properties["port"].ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Matches(t, string(output), `"type": \[\s*"string",\s*"integer"\s*\]`)
+	assert.Matches(t, string(output), `"default": 80`)
+}
+
+func TestNoDefault(t *testing.T) {
+	data := `
+# @schema
+# noDefault: true
+# @schema
+password: changeme
+user: admin
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Properties["password"].Default, nil)
+	assert.Equal(t, s.Properties["user"].Default, "admin")
+}
+
+func TestConstFromValue(t *testing.T) {
+	data := `
+# @schema
+# constFromValue: true
+# @schema
+apiVersion: apps/v1
+# @schema
+# constFromValue: true
+# @schema
+replicas: 3
+kind: Deployment
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Properties["apiVersion"].Const, "apps/v1")
+	assert.Equal(t, s.Properties["apiVersion"].Default, nil)
+	assert.Equal(t, s.Properties["replicas"].Const, 3)
+	assert.Equal(t, s.Properties["replicas"].Default, nil)
+	assert.Equal(t, s.Properties["kind"].Const, nil)
+	assert.Equal(t, s.Properties["kind"].Default, "Deployment")
+
+	output, err := s.Properties["apiVersion"].ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if strings.Contains(string(output), "default") || strings.Contains(string(output), "constFromValue") {
+		t.Errorf("Expected only the const in the output, but got %s", output)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+}
+
+func TestUnevaluatedProperties(t *testing.T) {
+	data := `
+# @schema
+# allOf:
+#   - properties:
+#       name:
+#         type: string
+# @schema
+composed:
+  name: foo
+plain:
+  name: foo
+`
+	tests := []struct {
+		enabled                       bool
+		schemaURI                     string
+		expectedAdditionalProperties  SchemaOrBool
+		expectedUnevaluatedProperties SchemaOrBool
+		expectedWarnings              int
+	}{
+		{
+			enabled:                      false,
+			expectedAdditionalProperties: new(bool),
+		},
+		{
+			enabled:                       true,
+			schemaURI:                     "https://json-schema.org/draft/2019-09/schema",
+			expectedUnevaluatedProperties: false,
+		},
+		{
+			// draft-07 doesn't know unevaluatedProperties
+			enabled:                      true,
+			expectedAdditionalProperties: new(bool),
+			expectedWarnings:             1,
+		},
+	}
+
+	hook := logtest.NewGlobal()
+	for _, test := range tests {
+		opts := &GenerateOptions{UnevaluatedProperties: test.enabled, SchemaURI: test.schemaURI}
+		s := generateTestSchema(t, data, opts)
+		assert.Equal(t, len(hook.AllEntries()), test.expectedWarnings)
+		hook.Reset()
+
+		composed := s.Properties["composed"]
+		assert.Equal(t, composed.AdditionalProperties, test.expectedAdditionalProperties)
+		assert.Equal(t, composed.UnevaluatedProperties, test.expectedUnevaluatedProperties)
+		// objects without composition keep using additionalProperties
+		assert.Equal(t, s.Properties["plain"].AdditionalProperties, new(bool))
+		assert.Equal(t, s.Properties["plain"].UnevaluatedProperties, nil)
+	}
+}
+
+func TestBooleanDefaults(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected interface{}
+	}{
+		{value: "yes", expected: true},
+		{value: "no", expected: false},
+		{value: "on", expected: true},
+		{value: "Off", expected: false},
+		{value: "True", expected: true},
+		{value: "false", expected: false},
+		{value: "maybe", expected: "maybe"},
+	}
+
+	for _, test := range tests {
+		data := fmt.Sprintf(`
+# @schema
+# type: boolean
+# @schema
+enabled: %s
+`, test.value)
+		s := generateTestSchema(t, data, &GenerateOptions{})
+		assert.Equal(t, s.Properties["enabled"].Default, test.expected)
+	}
+}
+
+func TestValidatePatternPropertiesKeys(t *testing.T) {
+	comment := `
+# @schema
+# patternProperties:
+#   "^[a-z]+$":
+#     type: string
+#   "^[a-z+$":
+#     type: string
+# @schema`
+	s, _, err := GetSchemaFromComment(comment)
+	if err != nil {
+		t.Fatalf("Error while parsing comment: %v", err)
+	}
+	err = s.Validate()
+	if err == nil {
+		t.Fatalf("Expected an error for the invalid patternProperties key")
+	}
+	if !strings.Contains(err.Error(), "^[a-z+$") {
+		t.Errorf("Expected the error to contain the invalid pattern, but got: %v", err)
+	}
+}
+
+func TestTypeFromTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected []string
+	}{
+		{tag: "!!null", expected: []string{"null"}},
+		{tag: "!!bool", expected: []string{"boolean"}},
+		{tag: "!!str", expected: []string{"string"}},
+		{tag: "!!int", expected: []string{"integer"}},
+		{tag: "!!float", expected: []string{"number"}},
+		{tag: "!!timestamp", expected: []string{"string"}},
+		{tag: "!!seq", expected: []string{"array"}},
+		{tag: "!!map", expected: []string{"object"}},
+	}
+
+	for _, test := range tests {
+		types, err := TypeFromTag(test.tag)
+		if err != nil {
+			t.Errorf("Wasn't expecting an error for tag %s, but got this: %v", test.tag, err)
+		}
+		assert.Equal(t, types, test.expected)
+	}
+
+	if _, err := TypeFromTag("!!binary"); err == nil {
+		t.Errorf("Expected an error for an unsupported tag")
+	}
+}
+
+func TestKubernetesNameKeys(t *testing.T) {
+	data := `
+serviceName: my-service
+# @schema
+# maxLength: 20
+# @schema
+configMapName: config
+hostname: chart-example.local
+secretName: tls.example.com
+# the value isn't a kubernetes name
+userName: John Doe
+replicas: 1
+`
+	hook := logtest.NewGlobal()
+	opts := &GenerateOptions{Inference: InferenceOptions{KubernetesNameKeys: regexp.MustCompile(DefaultKubernetesNameKeyPattern)}}
+	s := generateTestSchema(t, data, opts)
+
+	serviceName := s.Properties["serviceName"]
+	assert.Equal(t, *serviceName.MaxLength, 253)
+	assert.Equal(t, serviceName.Pattern, kubernetesNamePattern)
+	// annotated constraints take precedence
+	assert.Equal(t, *s.Properties["configMapName"].MaxLength, 20)
+	assert.Equal(t, s.Properties["replicas"].MaxLength, (*int)(nil))
+	// the key of a host name doesn't match, names may contain dots
+	assert.Equal(t, s.Properties["hostname"].MaxLength, (*int)(nil))
+	assert.Equal(t, s.Properties["secretName"].Pattern, kubernetesNamePattern)
+	// keys with other values are skipped with a warning
+	assert.Equal(t, s.Properties["userName"].Pattern, "")
+	assert.Equal(t, len(hook.AllEntries()), 1)
+	hook.Reset()
+
+	// the defaults are valid against the constraints
+	violations, err := s.DefaultViolations()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, violations, []string{})
+	if err := s.Validate(); err != nil {
+		t.Errorf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	s = generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, s.Properties["serviceName"].MaxLength, (*int)(nil))
+	assert.Equal(t, s.Properties["serviceName"].Pattern, "")
+}
+
+func TestGetSchemaFromCommentMultipleDocuments(t *testing.T) {
+	comment := `
+# @schema
+# type: string
+# ---
+# minLength: 1
+# @schema`
+	if _, _, err := GetSchemaFromComment(comment); err == nil {
+		t.Errorf("Expected an error for a schema block with multiple documents")
+	}
+
+	comment = `
+# @schema
+# ---
+# type: string
+# @schema`
+	s, _, err := GetSchemaFromComment(comment)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Type, StringOrArrayOfString{"string"})
+}
+
+func TestHelmDocsTitle(t *testing.T) {
+	data := `
+# -- (string) The name of the service. Defaults to the release name.
+serviceName: ""
+# -- Number of replicas
+replicas: 1
+# The port
+port: 80
+`
+	tests := []struct {
+		opts                *GenerateOptions
+		expectedTitle       map[string]string
+		expectedDescription map[string]string
+	}{
+		{
+			opts: &GenerateOptions{HelmDocsTitle: true},
+			expectedTitle: map[string]string{
+				"serviceName": "The name of the service",
+				"replicas":    "Number of replicas",
+				// no helm-docs comment
+				"port": "port",
+			},
+			expectedDescription: map[string]string{
+				"serviceName": "Defaults to the release name.",
+				"replicas":    "",
+				"port":        "The port",
+			},
+		},
+		{
+			opts:          &GenerateOptions{},
+			expectedTitle: map[string]string{"serviceName": "serviceName", "replicas": "replicas", "port": "port"},
+			expectedDescription: map[string]string{
+				"serviceName": "(string) The name of the service. Defaults to the release name.",
+				"replicas":    "Number of replicas",
+				"port":        "The port",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s := generateTestSchema(t, data, test.opts)
+		for key, title := range test.expectedTitle {
+			assert.Equal(t, s.Properties[key].Title, title)
+			assert.Equal(t, s.Properties[key].Description, test.expectedDescription[key])
+		}
+	}
+}
+
+func TestEmptyValues(t *testing.T) {
+	for _, data := range []string{"", "# only\n# comments\n", "---\n"} {
+		s := generateTestSchema(t, data, &GenerateOptions{})
+
+		assert.Equal(t, s.Type, StringOrArrayOfString{"object"})
+		assert.Equal(t, s.Schema, "http://json-schema.org/draft-07/schema#")
+		assert.Equal(t, s.AdditionalProperties, new(bool))
+		assert.Equal(t, len(s.Properties), 1)
+		if _, ok := s.Properties["global"]; !ok {
+			t.Errorf("Expected the global property for values %q", data)
+		}
+		if err := s.Validate(); err != nil {
+			t.Errorf("Wasn't expecting an error for values %q, but got this: %v", data, err)
+		}
+	}
+}
+
+func TestReadOnlyNotRequired(t *testing.T) {
+	data := `
+app:
+  # @schema
+  # readOnly: true
+  # required: true
+  # @schema
+  status: ready
+  name: foo
+`
+	tests := []struct {
+		enabled  bool
+		expected []string
+	}{
+		{enabled: false, expected: []string{"status", "name"}},
+		{enabled: true, expected: []string{"name"}},
+	}
+
+	for _, test := range tests {
+		opts := &GenerateOptions{ReadOnlyNotRequired: test.enabled}
+		s := generateTestSchema(t, data, opts)
+		assert.Equal(t, s.Properties["app"].Required.Strings, test.expected)
+	}
+
+	// annotated properties are never required either
+	data = `# @schema
+# properties:
+#   status:
+#     type: string
+#     readOnly: true
+#     required: true
+#   name:
+#     type: string
+#     required: true
+# @schema
+
+status: ready
+`
+	for _, test := range []struct {
+		enabled  bool
+		expected []string
+	}{
+		{enabled: false, expected: []string{"name", "status"}},
+		{enabled: true, expected: []string{"name"}},
+	} {
+		opts := &GenerateOptions{ReadOnlyNotRequired: test.enabled}
+		s := generateTestSchema(t, data, opts)
+		required := s.Required.Strings
+		slices.Sort(required)
+		assert.Equal(t, required, test.expected)
+	}
+}
+
+func TestEnumTypes(t *testing.T) {
+	data := `
+# @schema
+# enum: [1, 2, 3]
+# @schema
+replicas: 1
+# @schema
+# enum: [true, "auto"]
+# @schema
+tls: auto
+# @schema
+# enum: [0.5, 1.5]
+# @schema
+ratio: 0.5
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Properties["replicas"].Enum, []interface{}{1, 2, 3})
+	assert.Equal(t, s.Properties["tls"].Enum, []interface{}{true, "auto"})
+
+	output, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, expected := range []string{`"enum":[1,2,3]`, `"enum":[true,"auto"]`, `"enum":[0.5,1.5]`} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected %s in the output, but got %s", expected, output)
+		}
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+}
+
+func TestMaxEnumSize(t *testing.T) {
+	data := `
+# @schema
+# enum: [a, b, c, d]
+# @schema
+region: a
+# @schema
+# enum: [x, y]
+# @schema
+size: x
+`
+	tests := []struct {
+		overflow         EnumOverflow
+		expectedEnum     []interface{}
+		expectedType     StringOrArrayOfString
+		expectedWarnings int
+	}{
+		{
+			overflow:         EnumOverflowWarn,
+			expectedEnum:     []interface{}{"a", "b", "c", "d"},
+			expectedType:     nil,
+			expectedWarnings: 1,
+		},
+		{
+			overflow:         EnumOverflowDrop,
+			expectedEnum:     nil,
+			expectedType:     StringOrArrayOfString{"string"},
+			expectedWarnings: 0,
+		},
+	}
+
+	hook := logtest.NewGlobal()
+	for _, test := range tests {
+		opts := &GenerateOptions{MaxEnumSize: 3, EnumOverflow: test.overflow}
+		s := generateTestSchema(t, data, opts)
+
+		assert.Equal(t, s.Properties["region"].Enum, test.expectedEnum)
+		assert.Equal(t, s.Properties["region"].Type, test.expectedType)
+		assert.Equal(t, s.Properties["size"].Enum, []interface{}{"x", "y"})
+		assert.Equal(t, len(hook.AllEntries()), test.expectedWarnings)
+		hook.Reset()
+	}
+}
+
+func TestEnumTitles(t *testing.T) {
+	data := `
+# @schema
+# enum: [gp2, gp3, io1]
+# x-enumDescriptions: [General purpose, General purpose (latest), Provisioned IOPS]
+# @schema
+volumeType: gp3
+# @schema
+# enum: [a, b]
+# @schema
+unlabeled: a
+`
+	s := generateTestSchema(t, data, &GenerateOptions{EnumTitles: true})
+	volumeType := s.Properties["volumeType"]
+	assert.Equal(t, volumeType.Enum == nil, true)
+	assert.Equal(t, len(volumeType.OneOf), 3)
+	assert.Equal(t, volumeType.OneOf[1].Const, "gp3")
+	assert.Equal(t, volumeType.OneOf[1].Title, "General purpose (latest)")
+	assert.Equal(t, s.Properties["unlabeled"].Enum, []interface{}{"a", "b"})
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+
+	output, err := volumeType.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	first := parsed["oneOf"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, first["const"], "gp2")
+	assert.Equal(t, first["title"], "General purpose")
+	if _, ok := parsed[EnumDescriptionsAnnotation]; ok {
+		t.Errorf("Expected %s to be removed, but got %s", EnumDescriptionsAnnotation, output)
+	}
+
+	// without the option the enum is kept
+	s = generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, s.Properties["volumeType"].Enum, []interface{}{"gp2", "gp3", "io1"})
+	assert.Equal(t, s.Properties["volumeType"].OneOf == nil, true)
+
+	// the labels must match the enum members
+	invalid := &Schema{
+		Enum:              []interface{}{"a", "b"},
+		CustomAnnotations: map[string]interface{}{EnumDescriptionsAnnotation: []interface{}{"A"}},
+	}
+	if err := invalid.enumToOneOf(); err == nil {
+		t.Error("Expected an error for x-enumDescriptions with fewer labels than enum members")
+	}
+}
+
+func TestStrictTypes(t *testing.T) {
+	data := `
+# an ambiguous key
+ambiguous:
+# @schema
+# type: [string, "null"]
+# @schema
+annotated:
+# @schema
+# enum: [a, b]
+# @schema
+enumerated:
+typed: foo
+`
+	tests := []struct {
+		enabled          bool
+		expectedWarnings int
+	}{
+		{enabled: false, expectedWarnings: 0},
+		{enabled: true, expectedWarnings: 1},
+	}
+
+	hook := logtest.NewGlobal()
+	for _, test := range tests {
+		opts := &GenerateOptions{StrictTypes: test.enabled}
+		s := generateTestSchema(t, data, opts)
+
+		assert.Equal(t, s.Properties["ambiguous"].Type, StringOrArrayOfString(nil))
+		assert.Equal(t, len(hook.AllEntries()), test.expectedWarnings)
+		if test.expectedWarnings > 0 {
+			assert.Equal(t, hook.LastEntry().Message, "The type of key ambiguous (line 3) can't be inferred from its empty value, please annotate its type")
+		}
+		hook.Reset()
+	}
+}
+
+func TestNormalizeDescription(t *testing.T) {
+	tests := []struct {
+		data            string
+		keepFullComment bool
+		expected        string
+	}{
+		{
+			data:     "# First line   \n#\n#\n#\n# Second line\t\n#\n# Third line\nfoo: bar\n",
+			expected: "First line\n\nSecond line\n\nThird line",
+		},
+		{
+			data:            "# First line   \n#\n#\n#\n# Second line\t\n#\n# Third line\nfoo: bar\n",
+			keepFullComment: true,
+			expected:        "First line   \n\n\n\nSecond line\t\n\nThird line",
+		},
+		{
+			// two blank lines are collapsed as well
+			data:     "# First line\n#\n#\n# Second line\nfoo: bar\n",
+			expected: "First line\n\nSecond line",
+		},
+	}
+
+	for _, test := range tests {
+		s := generateTestSchema(t, test.data, &GenerateOptions{KeepFullComment: test.keepFullComment})
+		assert.Equal(t, s.Properties["foo"].Description, test.expected)
+	}
+}
+
+func TestAdditionalItems(t *testing.T) {
+	data := `
+# @schema
+# type: array
+# items:
+#   - type: string
+#   - type: integer
+# additionalItems: false
+# @schema
+pair: [foo, 1]
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	pair := s.Properties["pair"]
+	assert.Equal(t, len(pair.TupleItems), 2)
+	assert.Equal(t, pair.Items, (*Schema)(nil))
+	assert.Equal(t, pair.AdditionalItems, false)
+
+	output, err := json.Marshal(pair)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"additionalItems":false`) ||
+		!strings.Contains(string(output), `"items":[{"required":[],"type":"string"},{"required":[],"type":"integer"}]`) {
+		t.Errorf("Expected tuple items and additionalItems in the output, but got %s", output)
+	}
+
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# items:
+#   - type: string
+# additionalItems:
+#   type: integer
+#   minimum: 1
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# items:
+#   type: string
+# additionalItems: false
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# $schema: https://json-schema.org/draft/2020-12/schema
+# items:
+#   - type: string
+# additionalItems: false
+# @schema`,
+			expectedValid: false,
+		},
+	}
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		if valid := err == nil; valid != test.expectedValid {
+			t.Errorf("Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)", test.comment, test.expectedValid, valid, err)
+		}
+	}
+}
+
+func TestContainsValidation(t *testing.T) {
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# $schema: https://json-schema.org/draft/2020-12/schema
+# type: array
+# contains:
+#   const: admin
+# minContains: 1
+# maxContains: 2
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# type: array
+# minContains: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# type: array
+# maxContains: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# type: array
+# contains:
+#   const: admin
+# minContains: 3
+# maxContains: 2
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# type: string
+# contains:
+#   const: admin
+# @schema`,
+			expectedValid: false,
+		},
+	}
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		if valid := err == nil; valid != test.expectedValid {
+			t.Errorf("Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)", test.comment, test.expectedValid, valid, err)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	data := `
+# @schema
+# type: array
+# contains:
+#   properties:
+#     port:
+#       const: 443
+#       required: true
+# @schema
+ports:
+  - port: 443
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	contains := s.Properties["ports"].Contains
+	assert.Equal(t, contains.Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, contains.Required.Strings, []string{"port"})
+
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(string(output))); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for values, valid := range map[string]bool{
+		`{"ports": [{"port": 80}, {"port": 443}]}`: true,
+		`{"ports": [{"port": 80}]}`:                false,
+		`{"ports": [{}]}`:                          false,
+	} {
+		var instance interface{}
+		if err := json.Unmarshal([]byte(values), &instance); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, compiled.Validate(instance) == nil, valid, values)
+	}
+}
+
+func TestItemsCountValidation(t *testing.T) {
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# type: array
+# minItems: 1
+# maxItems: 5
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# minItems: 1
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# type: array
+# minItems: 5
+# maxItems: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# type: string
+# maxItems: 1
+# @schema`,
+			expectedValid: false,
+		},
+	}
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		if valid := err == nil; valid != test.expectedValid {
+			t.Errorf("Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)", test.comment, test.expectedValid, valid, err)
+		}
+	}
+
+	schema, _, err := GetSchemaFromComment(tests[0].comment)
+	if err != nil {
+		t.Fatalf("Error while parsing comment: %v", err)
+	}
+	output, err := schema.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"minItems": 1`) || !strings.Contains(string(output), `"maxItems": 5`) {
+		t.Errorf("Expected minItems and maxItems in the output, but got %s", output)
+	}
+}
+
+func TestPropertiesCountValidation(t *testing.T) {
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# type: object
+# minProperties: 1
+# maxProperties: 10
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# type: object
+# minProperties: 10
+# maxProperties: 1
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# type: array
+# minProperties: 1
+# @schema`,
+			expectedValid: false,
+		},
+	}
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		if valid := err == nil; valid != test.expectedValid {
+			t.Errorf("Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)", test.comment, test.expectedValid, valid, err)
+		}
+	}
+
+	data := `
+# @schema
+# minProperties: 1
+# maxProperties: 10
+# @schema
+extraLabels: {}
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, *s.Properties["extraLabels"].MinProperties, 1)
+	assert.Equal(t, *s.Properties["extraLabels"].MaxProperties, 10)
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+}
+
+func TestDependentRequired(t *testing.T) {
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# type: object
+# dependentRequired:
+#   enabled: [secretName]
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# type: string
+# dependentRequired:
+#   enabled: [secretName]
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# dependentRequired:
+#   enabled: [secretName, secretName]
+# @schema`,
+			expectedValid: false,
+		},
+		{
+			comment: `
+# @schema
+# dependentRequired:
+#   enabled: [enabled]
+# @schema`,
+			expectedValid: false,
+		},
+	}
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		if valid := err == nil; valid != test.expectedValid {
+			t.Errorf("Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)", test.comment, test.expectedValid, valid, err)
+		}
+	}
+
+	// the dependencies must be a list of property names
+	if _, _, err := GetSchemaFromComment(`
+# @schema
+# dependentRequired:
+#   enabled: secretName
+# @schema`); err == nil {
+		t.Error("Expected an error for dependentRequired which isn't a list of strings")
+	}
+
+	data := `
+# @schema
+# dependentRequired:
+#   enabled: [secretName]
+# @schema
+tls:
+  enabled: false
+  secretName: ""
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, s.Properties["tls"].DependentRequired, map[string][]string{"enabled": {"secretName"}})
+	output, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"dependentRequired": {`) {
+		t.Errorf("Expected dependentRequired in the output, but got %s", output)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	data := `
+# @schema
+# dependencies:
+#   tls: [secretName]
+#   proxy:
+#     properties:
+#       port:
+#         type: integer
+#     required: [port]
+# @schema
+server:
+  tls: false
+  secretName: ""
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	dependencies := s.Properties["server"].Dependencies
+	assert.Equal(t, dependencies["tls"].Strings, []string{"secretName"})
+	assert.Equal(t, dependencies["tls"].Schema == nil, true)
+	assert.Equal(t, dependencies["proxy"].Schema.Properties["port"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, dependencies["proxy"].Schema.Required.Strings, []string{"port"})
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected the schema to be valid, but got: %v", err)
+	}
+
+	output, err := s.Properties["server"].ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	parsedDependencies := parsed["dependencies"].(map[string]interface{})
+	assert.Equal(t, parsedDependencies["tls"], []interface{}{"secretName"})
+	assert.Equal(t, parsedDependencies["proxy"].(map[string]interface{})["required"], []interface{}{"port"})
+
+	// dependencies can be read from json, e.g. of referenced files
+	var fromJSON Schema
+	if err := json.Unmarshal(output, &fromJSON); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, fromJSON.Dependencies["tls"].Strings, []string{"secretName"})
+	assert.Equal(t, fromJSON.Dependencies["proxy"].Schema.Required.Strings, []string{"port"})
+
+	if _, _, err := GetSchemaFromComment(`
+# @schema
+# dependencies:
+#   tls: secretName
+# @schema`); err == nil {
+		t.Error("Expected an error for dependencies which are neither a schema nor a list of strings")
+	}
+}
+
+func TestUniqueItems(t *testing.T) {
+	tests := []struct {
+		comment       string
+		expectedValid bool
+	}{
+		{
+			comment: `
+# @schema
+# type: array
+# uniqueItems: true
+# @schema`,
+			expectedValid: true,
+		},
+		{
+			comment: `
+# @schema
+# type: object
+# uniqueItems: true
+# @schema`,
+			expectedValid: false,
+		},
+	}
+	for _, test := range tests {
+		schema, _, err := GetSchemaFromComment(test.comment)
+		if err != nil {
+			t.Fatalf("Error while parsing comment %s: %v", test.comment, err)
+		}
+		err = schema.Validate()
+		if valid := err == nil; valid != test.expectedValid {
+			t.Errorf("Expected schema\n%s\n\n to be valid=%t, but it's %t (%v)", test.comment, test.expectedValid, valid, err)
+		}
+	}
+
+	data := `
+# @schema
+# uniqueItems: true
+# @schema
+allowedIPs:
+  - 10.0.0.1
+  - 10.0.0.2
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, s.Properties["allowedIPs"].UniqueItems, true)
+
+	output, err := s.Properties["allowedIPs"].ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Matches(t, string(output), `"uniqueItems": true`)
+}
+
+func TestScalarTypeUnion(t *testing.T) {
+	data := `
+mixed:
+  - foo
+  - 1
+  - true
+  - bar
+withObject:
+  - foo
+  - name: bar
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	mixed := s.Properties["mixed"].Items
+	assert.Equal(t, mixed.Type, StringOrArrayOfString{"string", "integer", "boolean"})
+	assert.Equal(t, len(mixed.AnyOf), 0)
+
+	output, err := mixed.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Matches(t, string(output), `"type": \[\s*"string",\s*"integer",\s*"boolean"\s*\]`)
+
+	// objects can't be part of a type union
+	assert.Equal(t, len(s.Properties["withObject"].Items.AnyOf), 2)
+}
+
+func TestSet(t *testing.T) {
+	data := `
+# @schema
+# set: true
+# default: [c, a, b]
+# @schema
+zones: [a]
+# @schema
+# set: true
+# default: [10, 9, 1]
+# @schema
+ports: [80]
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Properties["zones"].UniqueItems, true)
+	// the order of the default is kept by default
+	assert.Equal(t, s.Properties["zones"].Default, []interface{}{"c", "a", "b"})
+
+	s = generateTestSchema(t, data, &GenerateOptions{SortSetDefaults: true})
+	assert.Equal(t, s.Properties["zones"].Default, []interface{}{"a", "b", "c"})
+	assert.Equal(t, s.Properties["ports"].Default, []interface{}{1, 9, 10})
+
+	output, err := s.Properties["zones"].ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Matches(t, string(output), `"uniqueItems": true`)
+	if strings.Contains(string(output), `"set"`) {
+		t.Errorf("Expected the set annotation to be expanded, but got %s", output)
+	}
+}
+
+func TestFractionalMultipleOf(t *testing.T) {
+	data := `
+# @schema
+# multipleOf: 0.01
+# @schema
+price: 9.99
+# @schema
+# multipleOf: 0.0000001
+# @schema
+ratio: 0.5
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, *s.Properties["price"].MultipleOf, 0.01)
+	output, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"multipleOf":0.01,`) || !strings.Contains(string(output), `"multipleOf":0.0000001,`) {
+		t.Errorf("Expected the exact multipleOf values in the output, but got %s", output)
+	}
+}
+
+func TestZeroDefaults(t *testing.T) {
+	data := `
+# @schema
+# type: string
+# @schema
+name:
+# @schema
+# type: integer
+# @schema
+replicas:
+# @schema
+# type: number
+# @schema
+ratio: ~
+# @schema
+# type: boolean
+# @schema
+enabled: null
+# @schema
+# type: array
+# @schema
+hosts:
+# @schema
+# type: object
+# @schema
+labels:
+untyped:
+`
+	s := generateTestSchema(t, data, &GenerateOptions{ZeroDefaults: true})
+
+	assert.Equal(t, s.Properties["name"].Default, "")
+	assert.Equal(t, s.Properties["replicas"].Default, 0)
+	assert.Equal(t, s.Properties["ratio"].Default, 0)
+	assert.Equal(t, s.Properties["enabled"].Default, false)
+	assert.Equal(t, s.Properties["hosts"].Default, []interface{}{})
+	assert.Equal(t, s.Properties["labels"].Default, map[string]interface{}{})
+	assert.Equal(t, s.Properties["untyped"].Default, nil)
+
+	output, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, expected := range []string{`"default":""`, `"default":0`, `"default":false`, `"default":[]`, `"default":{}`} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected %s in the output, but got %s", expected, output)
+		}
+	}
+
+	// disabled by default
+	s = generateTestSchema(t, data, &GenerateOptions{})
+	assert.Equal(t, s.Properties["name"].Default, nil)
+}
+
+func TestConstTypes(t *testing.T) {
+	data := `
+# @schema
+# const: 8080
+# @schema
+port: 8080
+# @schema
+# const: true
+# @schema
+enabled: true
+# @schema
+# enum: [1, 2, 3]
+# @schema
+replicas: 1
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	output, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, expected := range []string{`"const":8080,"default":8080`, `"const":true,"default":true`, `"default":1,"enum":[1,2,3]`} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected %s in the output, but got %s", expected, output)
+		}
+	}
+
+	// the default must match the const
+	schema, _, err := GetSchemaFromComment(`
+# @schema
+# const: 8080
+# default: 80
+# @schema`)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := schema.Validate(); err == nil || !strings.Contains(err.Error(), "doesn't match the const") {
+		t.Errorf("Expected the mismatching default to be invalid, but got: %v", err)
+	}
+}
+
+func TestNullForms(t *testing.T) {
+	var expected []byte
+	for _, value := range []string{"", "~", "null"} {
+		data := fmt.Sprintf("foo: %s\n", value)
+		s := generateTestSchema(t, data, &GenerateOptions{})
+
+		foo := s.Properties["foo"]
+		assert.Equal(t, foo.Type, StringOrArrayOfString(nil))
+		assert.Equal(t, foo.Default, nil)
+		assert.Equal(t, s.Required.Strings, []string{"foo"})
+
+		output, err := s.ToJson()
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if expected == nil {
+			expected = output
+		}
+		assert.Equal(t, string(output), string(expected))
+	}
+}
+
+func TestTypedExamples(t *testing.T) {
+	data := `
+# @schema
+# examples:
+#   - {a: 1, b: [x, y]}
+# @schema
+config: {}
+# @schema
+# examples: [1, 2.5, true]
+# @schema
+replicas: 1
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.Properties["config"].Examples, []interface{}{
+		map[string]interface{}{"a": 1, "b": []interface{}{"x", "y"}},
+	})
+	assert.Equal(t, s.Properties["replicas"].Examples, []interface{}{1, 2.5, true})
+
+	output, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, expected := range []string{`"examples":[{"a":1,"b":["x","y"]}]`, `"examples":[1,2.5,true]`} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected %s in the output, but got %s", expected, output)
+		}
+	}
+}
+
+func TestArrayAndItemExamples(t *testing.T) {
+	data := `
+# @schema
+# type: array
+# examples:
+#   - [a, b]
+#   - []
+# items:
+#   type: string
+#   examples: [foo, bar]
+# @schema
+list: [x]
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	list := s.Properties["list"]
+	assert.Equal(t, list.Examples, []interface{}{[]interface{}{"a", "b"}, []interface{}{}})
+	assert.Equal(t, list.Items.Examples, []interface{}{"foo", "bar"})
+
+	output, err := json.Marshal(list)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"examples":[["a","b"],[]]`) ||
+		!strings.Contains(string(output), `"items":{"examples":["foo","bar"]`) {
+		t.Errorf("Expected array and item examples in the output, but got %s", output)
+	}
+}
+
+func TestRequiredPropertiesAlias(t *testing.T) {
+	data := `
+# @schema
+# requiredProperties: [password]
+# @schema
+database:
+  host: localhost
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	database := s.Properties["database"]
+	assert.Equal(t, database.Required.Strings, []string{"password", "host"})
+	assert.Equal(t, database.RequiredProperties, []string(nil))
+
+	output, err := json.Marshal(database)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(output), `"required":["password","host"]`) || strings.Contains(string(output), "requiredProperties") {
+		t.Errorf("Expected the required array in the output, but got %s", output)
+	}
+}
+
+func TestDraft04Keywords(t *testing.T) {
+	data := `# @schema
+# $schema: http://json-schema.org/draft-04/schema#
+# $id: https://example.org/values.json
+# $defs:
+#   port:
+#     type: integer
+# @schema
+
+# @schema
+# $id: https://example.org/service.json
+# @schema
+service:
+  port: 80
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+	output, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, result["id"], "https://example.org/values.json")
+	assert.Equal(t, result["definitions"], map[string]interface{}{"port": map[string]interface{}{"type": "integer"}})
+	service := result["properties"].(map[string]interface{})["service"].(map[string]interface{})
+	assert.Equal(t, service["id"], "https://example.org/service.json")
+	for _, keyword := range []string{`"$id"`, `"$defs"`, `"required":[]`} {
+		if strings.Contains(string(output), keyword) {
+			t.Errorf("Expected no %s in the draft-04 output, but got %s", keyword, output)
+		}
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Wasn't expecting an error, but got this: %v", err)
+	}
+}
+
+func TestDraft04Conversion(t *testing.T) {
+	draft04 := "http://json-schema.org/draft-04/schema#"
+	zero, ten := 0, 10
+	tests := []struct {
+		schema   *Schema
+		expected string
+	}{
+		{
+			schema:   &Schema{Schema: draft04, Type: StringOrArrayOfString{"integer"}, ExclusiveMinimum: &zero, ExclusiveMaximum: &ten},
+			expected: `{"$schema":"http://json-schema.org/draft-04/schema#","exclusiveMaximum":true,"exclusiveMinimum":true,"maximum":10,"minimum":0,"type":"integer"}`,
+		},
+		{
+			schema: &Schema{
+				Schema:     draft04,
+				Defs:       map[string]*Schema{"network.port": {Type: StringOrArrayOfString{"integer"}}},
+				Properties: map[string]*Schema{"port": {Ref: "#/$defs/network.port"}},
+			},
+			expected: `{"$schema":"http://json-schema.org/draft-04/schema#","definitions":{"network.port":{"type":"integer"}},"properties":{"port":{"$ref":"#/definitions/network.port"}}}`,
+		},
+		{
+			// the tuple items are added after the conversion of the other keywords
+			schema: &Schema{
+				Schema:     draft04,
+				Type:       StringOrArrayOfString{"array"},
+				TupleItems: []*Schema{{Ref: "#/$defs/port"}, {Type: StringOrArrayOfString{"number"}, ExclusiveMinimum: &zero}},
+			},
+			expected: `{"$schema":"http://json-schema.org/draft-04/schema#","items":[{"$ref":"#/definitions/port"},{"exclusiveMinimum":true,"minimum":0,"type":"number"}],"type":"array"}`,
+		},
+	}
+
+	for _, test := range tests {
+		output, err := json.Marshal(test.schema)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, string(output), test.expected)
+	}
+}
+
+func TestGetSchemaFromCommentMultipleBlocks(t *testing.T) {
+	comment := `
+# @schema
+# type: integer
+# properties:
+#   port:
+#     type: integer
+# @schema
+# @schema
+# minimum: 1
+# type: [integer, string]
+# properties:
+#   port:
+#     maximum: 65535
+# @schema
+# The description`
+	s, description, err := GetSchemaFromComment(comment)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.HasData, true)
+	assert.Equal(t, s.Type, StringOrArrayOfString{"integer", "string"})
+	assert.Equal(t, *s.Minimum, 1)
+	assert.Equal(t, s.Properties["port"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, *s.Properties["port"].Maximum, 65535)
+	assert.Equal(t, description, "\nThe description")
+}
+
+func TestGetSchemaFromCommentLongLine(t *testing.T) {
+	blob := strings.Repeat("A", 100*1024)
+	comment := "# @schema\n# type: string\n# @schema\n# " + blob
+	s, description, err := GetSchemaFromComment(comment)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, description, blob)
+}
+
+func TestNullHandling(t *testing.T) {
+	data := `
+empty: null
+# @schema
+# type: string
+# @schema
+annotated: null
+`
+	tests := []struct {
+		handling          NullHandling
+		expectedEmpty     StringOrArrayOfString
+		expectedAnnotated StringOrArrayOfString
+	}{
+		{
+			handling:          NullDrop,
+			expectedEmpty:     nil,
+			expectedAnnotated: StringOrArrayOfString{"string"},
+		},
+		{
+			handling:          NullStrict,
+			expectedEmpty:     StringOrArrayOfString{"null"},
+			expectedAnnotated: StringOrArrayOfString{"string"},
+		},
+		{
+			handling:          NullPermissive,
+			expectedEmpty:     nil,
+			expectedAnnotated: StringOrArrayOfString{"string", "null"},
+		},
+	}
+
+	for _, test := range tests {
+		opts := &GenerateOptions{Inference: InferenceOptions{NullHandling: test.handling}}
+		s := generateTestSchema(t, data, opts)
+		assert.Equal(t, s.Properties["empty"].Type, test.expectedEmpty)
+		assert.Equal(t, s.Properties["annotated"].Type, test.expectedAnnotated)
+	}
+
+	if _, err := NewNullHandling("unknown"); err == nil {
+		t.Errorf("Expected an error for an unsupported null handling")
+	}
+}
+
+func TestCoerceExamples(t *testing.T) {
+	data := `
+# @schema
+# examples: ["1", "3"]
+# @schema
+replicas: 2
+# @schema
+# type: [string, integer]
+# examples: ["8080"]
+# @schema
+port: http
+`
+	tests := []struct {
+		enabled          bool
+		expectedReplicas []interface{}
+	}{
+		{enabled: false, expectedReplicas: []interface{}{"1", "3"}},
+		{enabled: true, expectedReplicas: []interface{}{1, 3}},
+	}
+
+	for _, test := range tests {
+		opts := &GenerateOptions{CoerceExamples: test.enabled}
+		s := generateTestSchema(t, data, opts)
+		assert.Equal(t, s.Properties["replicas"].Examples, test.expectedReplicas)
+		// strings are valid for this key
+		assert.Equal(t, s.Properties["port"].Examples, []interface{}{"8080"})
+	}
+}
@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SplitRootFile is the name of the root schema written by WriteSplit
+const SplitRootFile = "values.schema.json"
+
+var nonFileNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// pointerEscaper escapes a key for the use in a json pointer
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// WriteSplit writes the subschema of every top-level property to its own file (e.g. image.schema.json) in dir and
+// the root schema to SplitRootFile, which references them with relative file refs. Local refs (e.g. to the
+// $defs of the root schema) are rewritten, so they still resolve.
+func WriteSplit(dir string, s *Schema) error {
+	keys := make([]string, 0, len(s.Properties))
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	// every key gets its own file, even if its name has to be sanitized
+	files := make(map[string]string, len(keys))
+	used := map[string]bool{SplitRootFile: true}
+	for _, key := range keys {
+		base := strings.Trim(nonFileNameChars.ReplaceAllString(key, "_"), "_.")
+		if base == "" {
+			base = "property"
+		}
+		name := base + ".schema.json"
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d.schema.json", base, i)
+		}
+		used[name] = true
+		files[key] = name
+	}
+
+	root := *s
+	root.Properties = make(map[string]*Schema, len(keys))
+	for _, key := range keys {
+		root.Properties[key] = &Schema{Ref: files[key]}
+
+		property := *s.Properties[key]
+		// the subschema is a document on its own, which uses the dialect of the root schema
+		property.Schema = s.Schema
+		if err := writeSplitFile(filepath.Join(dir, files[key]), &property, files); err != nil {
+			return err
+		}
+	}
+	return writeSplitFile(filepath.Join(dir, SplitRootFile), &root, files)
+}
+
+// writeSplitFile writes the schema to the path, its local refs are rewritten to point to the split files
+func writeSplitFile(path string, s *Schema, files map[string]string) error {
+	data, err := s.ToJson()
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(rewriteSplitRefs(value, files), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// rewriteSplitRefs points local refs into a top-level property to the file of the property and other local refs
+// to the root file
+func rewriteSplitRefs(value interface{}, files map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if ref, ok := item.(string); ok && key == "$ref" && strings.HasPrefix(ref, "#") {
+				v[key] = splitRef(ref, files)
+				continue
+			}
+			v[key] = rewriteSplitRefs(item, files)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rewriteSplitRefs(item, files)
+		}
+	}
+	return value
+}
+
+// splitRef returns the file ref for the local ref
+func splitRef(ref string, files map[string]string) string {
+	for key, file := range files {
+		prefix := "#/properties/" + pointerEscaper.Replace(key)
+		if ref == prefix {
+			return file
+		}
+		if strings.HasPrefix(ref, prefix+"/") {
+			return file + "#" + strings.TrimPrefix(ref, prefix)
+		}
+	}
+	return SplitRootFile + ref
+}
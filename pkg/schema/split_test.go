@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func TestWriteSplit(t *testing.T) {
+	minimum := 1
+	s := &Schema{
+		Schema: DefaultSchemaURI,
+		Type:   StringOrArrayOfString{"object"},
+		Defs: map[string]*Schema{
+			"port": {Type: StringOrArrayOfString{"integer"}, Minimum: &minimum},
+		},
+		Properties: map[string]*Schema{
+			"image": {
+				Type: StringOrArrayOfString{"object"},
+				Properties: map[string]*Schema{
+					"tag": {Type: StringOrArrayOfString{"string"}},
+				},
+			},
+			"port":   {Ref: "#/$defs/port"},
+			"web/ui": {Ref: "#/properties/image"},
+		},
+		Required: NewBoolOrArrayOfString([]string{"image"}, false),
+	}
+	dir := t.TempDir()
+	if err := WriteSplit(dir, s); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	read := func(name string) map[string]interface{} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected the file %s to be written, but got: %v", name, err)
+		}
+		var value map[string]interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	// the root file references the files of the top-level properties
+	root := read(SplitRootFile)
+	properties := root["properties"].(map[string]interface{})
+	ref := func(key string) interface{} {
+		return properties[key].(map[string]interface{})["$ref"]
+	}
+	assert.Equal(t, ref("image"), "image.schema.json")
+	assert.Equal(t, ref("port"), "port.schema.json")
+	assert.Equal(t, ref("web/ui"), "web_ui.schema.json")
+	assert.Equal(t, root["required"], []interface{}{"image"})
+
+	// the subschemas are the ones of the original schema, their local refs point to the split files
+	image := read("image.schema.json")
+	assert.Equal(t, image["$schema"], DefaultSchemaURI)
+	delete(image, "$schema")
+	original, err := s.Properties["image"].ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var originalImage map[string]interface{}
+	if err := json.Unmarshal(original, &originalImage); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image, originalImage)
+	assert.Equal(t, read("port.schema.json")["$ref"], SplitRootFile+"#/$defs/port")
+	assert.Equal(t, read("web_ui.schema.json")["$ref"], "image.schema.json")
+
+	// the refs resolve
+	compiled, err := jsonschema.NewCompiler().Compile(filepath.Join(dir, SplitRootFile))
+	if err != nil {
+		t.Fatalf("Expected the split schema to compile, but got: %v", err)
+	}
+	tests := []struct {
+		values string
+		valid  bool
+	}{
+		{values: `{"image": {"tag": "latest"}, "port": 80, "web/ui": {"tag": "v1"}}`, valid: true},
+		{values: `{"image": {"tag": "latest"}, "port": 0}`, valid: false},
+		{values: `{"image": {"tag": "latest"}, "web/ui": {"tag": 1}}`, valid: false},
+		{values: `{"port": 80}`, valid: false},
+	}
+	for _, test := range tests {
+		var values interface{}
+		if err := json.NewDecoder(strings.NewReader(test.values)).Decode(&values); err != nil {
+			t.Fatal(err)
+		}
+		err := compiled.Validate(values)
+		assert.Equal(t, err == nil, test.valid, test.values)
+	}
+}
@@ -0,0 +1,69 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Translations maps locales to the localized descriptions of the properties, which are keyed by their
+// dotted path (e.g. image.tag)
+type Translations map[string]map[string]string
+
+// ReadTranslations reads the translations from the yaml file
+func ReadTranslations(path string) (Translations, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var translations Translations
+	if err := yaml.Unmarshal(content, &translations); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return translations, nil
+}
+
+// applyTranslations replaces the descriptions of the properties by the ones of the Locale in the TranslationsFile
+func (o *GenerateOptions) applyTranslations(s *Schema) error {
+	translationsFile := o.TranslationsFile
+	if valuesPath := o.valuesPath(); !filepath.IsAbs(translationsFile) && valuesPath != "" {
+		translationsFile = filepath.Join(filepath.Dir(valuesPath), translationsFile)
+	}
+	translations, err := ReadTranslations(translationsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		o.warnf("The translations file %s doesn't exist", translationsFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	descriptions, ok := translations[o.Locale]
+	if !ok {
+		return fmt.Errorf("no translations found for the locale '%s' in %s", o.Locale, translationsFile)
+	}
+
+	applied := make(map[string]bool, len(descriptions))
+	s.WalkProperties(func(path string, property *Schema) {
+		if description, ok := descriptions[path]; ok {
+			property.Description = description
+			applied[path] = true
+		}
+	})
+
+	// translations of removed or renamed keys are likely outdated
+	paths := make([]string, 0, len(descriptions))
+	for path := range descriptions {
+		if !applied[path] {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		o.warnf("The key %s of the %s translations doesn't exist", path, o.Locale)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestTranslations(t *testing.T) {
+	dir := t.TempDir()
+	translations := `
+de:
+  image.tag: Der Tag des Images
+  removed: Veraltet
+fr:
+  image.tag: Le tag de l'image
+`
+	if err := os.WriteFile(filepath.Join(dir, "translations.yaml"), []byte(translations), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := `
+image:
+  # The tag of the image
+  tag: latest
+  # The repository of the image
+  repository: nginx
+`
+	opts := GenerateOptions{
+		ValuesPath:       filepath.Join(dir, "values.yaml"),
+		TranslationsFile: "translations.yaml",
+		Locale:           "de",
+	}
+	s, err := GenerateFromReader(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["image"].Properties["tag"].Description, "Der Tag des Images")
+	// keys without a translation keep their description
+	assert.Equal(t, s.Properties["image"].Properties["repository"].Description, "The repository of the image")
+
+	// translations of unknown keys are warnings
+	opts.FailOnWarning = true
+	if _, err := GenerateFromReader(strings.NewReader(data), opts); err == nil {
+		t.Error("Expected an error for the translation of an unknown key")
+	}
+
+	opts.Locale = "es"
+	if _, err := GenerateFromReader(strings.NewReader(data), opts); err == nil {
+		t.Error("Expected an error for a missing locale")
+	}
+}
+
+func TestTranslationsMissingFile(t *testing.T) {
+	opts := GenerateOptions{
+		ValuesPath:       filepath.Join(t.TempDir(), "values.yaml"),
+		TranslationsFile: "translations.yaml",
+		Locale:           "de",
+	}
+	data := "# The tag of the image\ntag: latest\n"
+	s, err := GenerateFromReader(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["tag"].Description, "The tag of the image")
+
+	// the missing file is a warning
+	opts.FailOnWarning = true
+	if _, err := GenerateFromReader(strings.NewReader(data), opts); err == nil {
+		t.Error("Expected an error for the missing translations file")
+	}
+}
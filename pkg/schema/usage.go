@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var valuesReferenceMatcher = regexp.MustCompile(`\.Values((?:\.[A-Za-z_][A-Za-z0-9_]*)+)`)
+
+// AnalyzeUsage compares the values referenced in the templates of the given chart with the values
+// declared in its values.yaml. It returns the (sorted) dotted paths of both and warns about values
+// which are used but not declared, or declared but never used.
+func AnalyzeUsage(chartDir string) (used, declared []string, err error) {
+	valuesFile, err := os.Open(filepath.Join(chartDir, "values.yaml"))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer valuesFile.Close()
+
+	valuesSchema, err := GenerateFromReader(valuesFile, GenerateOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	declared = propertyPaths(valuesSchema)
+
+	err = filepath.WalkDir(filepath.Join(chartDir, "templates"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range valuesReferenceMatcher.FindAllStringSubmatch(string(content), -1) {
+			reference := strings.TrimPrefix(match[1], ".")
+			if !slices.Contains(used, reference) {
+				used = append(used, reference)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Sort(used)
+
+	for _, reference := range used {
+		if !slices.ContainsFunc(declared, func(path string) bool { return isPathPrefix(path, reference) }) {
+			log.Warnf("The value %s is used in the templates, but not declared in the values", reference)
+		}
+	}
+	for _, path := range declared {
+		// global is a built-in helm object, which is mostly used by subcharts
+		if isPathPrefix("global", path) {
+			continue
+		}
+		if !slices.ContainsFunc(used, func(reference string) bool {
+			return isPathPrefix(path, reference) || isPathPrefix(reference, path)
+		}) {
+			log.Warnf("The value %s is declared in the values, but not used in the templates", path)
+		}
+	}
+
+	return used, declared, nil
+}
+
+// propertyPaths returns the sorted dotted paths of all (nested) properties
+func propertyPaths(s *Schema) []string {
+	paths := []string{}
+	s.WalkProperties(func(path string, _ *Schema) {
+		paths = append(paths, path)
+	})
+	slices.Sort(paths)
+	return paths
+}
+
+// isPathPrefix checks if the dotted path prefix equals the path or is one of its parents
+func isPathPrefix(prefix, path string) bool {
+	return prefix == path || strings.HasPrefix(path, prefix+".")
+}
@@ -0,0 +1,53 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestAnalyzeUsage(t *testing.T) {
+	chartDir := t.TempDir()
+	values := `
+image:
+  repository: nginx
+  tag: latest
+unused: true
+`
+	template := `
+image: {{ .Values.image.repository }}:{{ .Values.image.tag }}
+{{- if $.Values.missing.enabled }}
+enabled: true
+{{- end }}
+`
+	if err := os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(values), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chartDir, "templates", "deployment.yaml"), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	used, declared, err := AnalyzeUsage(chartDir)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, used, []string{"image.repository", "image.tag", "missing.enabled"})
+	assert.Equal(t, declared, []string{"global", "image", "image.repository", "image.tag", "unused"})
+
+	warnings := []string{}
+	for _, entry := range hook.AllEntries() {
+		warnings = append(warnings, entry.Message)
+	}
+	assert.Equal(t, warnings, []string{
+		"The value missing.enabled is used in the templates, but not declared in the values",
+		"The value unused is declared in the values, but not used in the templates",
+	})
+}
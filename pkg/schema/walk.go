@@ -0,0 +1,269 @@
+package schema
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+)
+
+// InternalAnnotation marks properties as internal, e.g. to hide them in documentation
+const InternalAnnotation = CustomAnnotationPrefix + "internal"
+
+// WalkProperties calls fn for all (nested) properties of the schema, sorted by their dotted path
+func (s *Schema) WalkProperties(fn func(path string, property *Schema)) {
+	s.walkProperties("", fn)
+}
+
+func (s *Schema) walkProperties(prefix string, fn func(path string, property *Schema)) {
+	keys := make([]string, 0, len(s.Properties))
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		fn(path, s.Properties[key])
+		s.Properties[key].walkProperties(path, fn)
+	}
+}
+
+// InternalPaths returns the dotted paths of all properties annotated with x-internal: true
+func (s *Schema) InternalPaths() []string {
+	paths := []string{}
+	s.WalkProperties(func(path string, property *Schema) {
+		if internal, ok := property.CustomAnnotations[InternalAnnotation].(bool); ok && internal {
+			paths = append(paths, path)
+		}
+	})
+	return paths
+}
+
+// RequiredReport returns for the dotted path of every (nested) property, whether it's required by its
+// parent. Keys which are only required conditionally (then or else, also within allOf) count as required.
+func (s *Schema) RequiredReport() map[string]bool {
+	report := map[string]bool{}
+	addRequired := func(prefix string, parent *Schema) {
+		required := parent.requiredKeys()
+		for key := range parent.Properties {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			report[path] = slices.Contains(required, key)
+		}
+	}
+	addRequired("", s)
+	s.WalkProperties(addRequired)
+	return report
+}
+
+// requiredKeys returns the required keys of the schema including the conditionally required ones
+func (s *Schema) requiredKeys() []string {
+	required := slices.Clone(s.Required.Strings)
+	for _, conditional := range []*Schema{s.Then, s.Else} {
+		if conditional != nil {
+			required = append(required, conditional.requiredKeys()...)
+		}
+	}
+	for _, subSchema := range s.AllOf {
+		required = append(required, subSchema.requiredKeys()...)
+	}
+	return required
+}
+
+// UndefinedRequiredProperties returns the dotted paths of the required keys which aren't defined in the
+// properties (or matched by the patternProperties) of their parent, e.g. after a property was removed by a merge.
+// Schemas with a $ref or allOf are skipped, because the properties may be defined there.
+func (s *Schema) UndefinedRequiredProperties() []string {
+	undefined := []string{}
+	check := func(prefix string, parent *Schema) {
+		if parent.Ref != "" || len(parent.AllOf) > 0 {
+			return
+		}
+		for _, key := range parent.Required.Strings {
+			if parent.definesProperty(key) {
+				continue
+			}
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			undefined = append(undefined, path)
+		}
+	}
+	check("", s)
+	s.WalkProperties(check)
+	return undefined
+}
+
+// UndefinedDependentRequiredProperties returns the dotted paths of the keys used in dependentRequired (as key or
+// as dependency), which aren't defined in the properties (or matched by the patternProperties) of the schema.
+// Schemas with a $ref or allOf are skipped, because the properties may be defined there.
+func (s *Schema) UndefinedDependentRequiredProperties() []string {
+	undefined := []string{}
+	check := func(prefix string, parent *Schema) {
+		if parent.Ref != "" || len(parent.AllOf) > 0 {
+			return
+		}
+		keys := make([]string, 0, len(parent.DependentRequired))
+		for key, dependencies := range parent.DependentRequired {
+			keys = append(keys, key)
+			keys = append(keys, dependencies...)
+		}
+		slices.Sort(keys)
+		for _, key := range slices.Compact(keys) {
+			if parent.definesProperty(key) {
+				continue
+			}
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			undefined = append(undefined, path)
+		}
+	}
+	check("", s)
+	s.WalkProperties(check)
+	return undefined
+}
+
+// definesProperty reports whether the key is defined in the properties or matched by the patternProperties
+func (s *Schema) definesProperty(key string) bool {
+	if _, ok := s.Properties[key]; ok {
+		return true
+	}
+	for pattern := range s.PatternProperties {
+		if matched, err := regexp.MatchString(pattern, key); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// PatternPropertyConflicts returns the dotted paths of the properties whose type contradicts the type of a
+// patternProperties schema matching their name. Both schemas apply to the key, so no value can be valid.
+func (s *Schema) PatternPropertyConflicts() []string {
+	conflicts := []string{}
+	check := func(prefix string, parent *Schema) {
+		keys := make([]string, 0, len(parent.Properties))
+		for key := range parent.Properties {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			property := parent.Properties[key]
+			for pattern, patternSchema := range parent.PatternProperties {
+				matched, err := regexp.MatchString(pattern, key)
+				if err != nil || !matched || typesOverlap(property.Type, patternSchema.Type) {
+					continue
+				}
+				path := key
+				if prefix != "" {
+					path = prefix + "." + key
+				}
+				conflicts = append(conflicts, path)
+				break
+			}
+		}
+	}
+	check("", s)
+	s.WalkProperties(check)
+	return conflicts
+}
+
+// typesOverlap reports whether a value can have both types, an empty type allows any value
+func typesOverlap(a, b StringOrArrayOfString) bool {
+	if a.IsEmpty() || b.IsEmpty() {
+		return true
+	}
+	for _, t := range a {
+		// integers are numbers as well
+		if b.Matches(t) || (t == "integer" && b.Matches("number")) || (t == "number" && b.Matches("integer")) {
+			return true
+		}
+	}
+	return false
+}
+
+// OneOfOverlap are two branches of a oneOf, which can't be told apart. A value matching one of them likely
+// matches the other one as well, so it doesn't match exactly one branch and is rejected.
+type OneOfOverlap struct {
+	// Path is the dotted path of the property with the oneOf, it's empty for the root schema
+	Path string
+	// First and Second are the indexes of the overlapping branches
+	First, Second int
+}
+
+// OneOfOverlaps returns the oneOf branches which don't differ in their type, const, enum, pattern, required keys
+// or the const of a property. This is a best-effort check for oneOfs which should have been anyOfs, branches with a
+// $ref are skipped.
+func (s *Schema) OneOfOverlaps() []OneOfOverlap {
+	overlaps := []OneOfOverlap{}
+	check := func(path string, schema *Schema) {
+		for i, first := range schema.OneOf {
+			for j := i + 1; j < len(schema.OneOf); j++ {
+				if branchesOverlap(first, schema.OneOf[j]) {
+					overlaps = append(overlaps, OneOfOverlap{Path: path, First: i, Second: j})
+				}
+			}
+		}
+	}
+	check("", s)
+	s.WalkProperties(check)
+	return overlaps
+}
+
+// branchesOverlap reports whether nothing obviously distinguishes the two schemas
+func branchesOverlap(a, b *Schema) bool {
+	if a == nil || b == nil || a.Ref != "" || b.Ref != "" {
+		return false
+	}
+	if !typesOverlap(a.Type, b.Type) || valuesDiffer(a, b) {
+		return false
+	}
+	if a.Pattern != "" && b.Pattern != "" && a.Pattern != b.Pattern {
+		return false
+	}
+
+	required := slices.Clone(a.Required.Strings)
+	otherRequired := slices.Clone(b.Required.Strings)
+	slices.Sort(required)
+	slices.Sort(otherRequired)
+	if !slices.Equal(slices.Compact(required), slices.Compact(otherRequired)) {
+		return false
+	}
+	for key, property := range a.Properties {
+		if otherProperty, ok := b.Properties[key]; ok && property != nil && otherProperty != nil &&
+			valuesDiffer(property, otherProperty) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesDiffer reports whether the const or enum of the schemas don't allow the same value
+func valuesDiffer(a, b *Schema) bool {
+	allowed := func(s *Schema) []interface{} {
+		if s.Const != nil {
+			return []interface{}{s.Const}
+		}
+		return s.Enum
+	}
+	first, second := allowed(a), allowed(b)
+	if first == nil || second == nil {
+		return false
+	}
+	for _, value := range first {
+		for _, other := range second {
+			if reflect.DeepEqual(value, other) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestInternalPaths(t *testing.T) {
+	data := `
+# @schema
+# x-internal: true
+# @schema
+debug: false
+service:
+  # @schema
+  # x-internal: true
+  # @schema
+  tracing: {}
+  port: 80
+  # @schema
+  # x-internal: false
+  # @schema
+  name: foo
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.InternalPaths(), []string{"debug", "service.tracing"})
+
+	// the annotation is emitted as well
+	output, err := s.Properties["debug"].ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Matches(t, string(output), `"x-internal": true`)
+}
+
+func TestRequiredReport(t *testing.T) {
+	s := &Schema{
+		Properties: map[string]*Schema{
+			"image": {
+				Properties: map[string]*Schema{
+					"repository": {Type: StringOrArrayOfString{"string"}},
+					"tag":        {Type: StringOrArrayOfString{"string"}},
+				},
+				Required: NewBoolOrArrayOfString([]string{"repository"}, false),
+			},
+			"tls": {
+				Properties: map[string]*Schema{
+					"enabled": {Type: StringOrArrayOfString{"boolean"}},
+					"secret":  {Type: StringOrArrayOfString{"string"}},
+					"issuer":  {Type: StringOrArrayOfString{"string"}},
+					"ca":      {Type: StringOrArrayOfString{"string"}},
+				},
+				If: &Schema{
+					Properties: map[string]*Schema{"enabled": {Const: true}},
+				},
+				Then: &Schema{Required: NewBoolOrArrayOfString([]string{"secret"}, false)},
+				AllOf: []*Schema{
+					{Else: &Schema{Required: NewBoolOrArrayOfString([]string{"issuer"}, false)}},
+				},
+			},
+		},
+		Required: NewBoolOrArrayOfString([]string{"image"}, false),
+	}
+
+	assert.Equal(t, s.RequiredReport(), map[string]bool{
+		"image":            true,
+		"image.repository": true,
+		"image.tag":        false,
+		"tls":              false,
+		"tls.enabled":      false,
+		"tls.secret":       true,
+		"tls.issuer":       true,
+		"tls.ca":           false,
+	})
+}
+
+func TestUndefinedRequiredProperties(t *testing.T) {
+	s := &Schema{
+		Properties: map[string]*Schema{
+			"service": {
+				Properties: map[string]*Schema{
+					"port": {Type: StringOrArrayOfString{"integer"}},
+				},
+				Required: NewBoolOrArrayOfString([]string{"port", "name"}, false),
+			},
+			"env": {
+				PatternProperties: map[string]*Schema{
+					"^[A-Z_]+$": {Type: StringOrArrayOfString{"string"}},
+				},
+				Required: NewBoolOrArrayOfString([]string{"LOG_LEVEL"}, false),
+			},
+			"image": {
+				Ref:      "image.json",
+				Required: NewBoolOrArrayOfString([]string{"repository"}, false),
+			},
+		},
+		Required: NewBoolOrArrayOfString([]string{"service", "replicas"}, false),
+	}
+
+	assert.Equal(t, s.UndefinedRequiredProperties(), []string{"replicas", "service.name"})
+}
+
+func TestUndefinedDependentRequiredProperties(t *testing.T) {
+	s := &Schema{
+		Properties: map[string]*Schema{
+			"tls": {
+				Properties: map[string]*Schema{
+					"enabled":    {Type: StringOrArrayOfString{"boolean"}},
+					"secretName": {Type: StringOrArrayOfString{"string"}},
+				},
+				DependentRequired: map[string][]string{
+					"enabled": {"secretName", "secretNamespace"},
+					"ca":      {"secretName"},
+				},
+			},
+			"image": {
+				Ref:               "image.json",
+				DependentRequired: map[string][]string{"tag": {"repository"}},
+			},
+		},
+		DependentRequired: map[string][]string{"tls": {"image"}},
+	}
+
+	assert.Equal(t, s.UndefinedDependentRequiredProperties(), []string{"tls.ca", "tls.secretNamespace"})
+}
+
+func TestPatternPropertyConflicts(t *testing.T) {
+	s := &Schema{
+		Properties: map[string]*Schema{
+			"foo":   {Type: StringOrArrayOfString{"string"}},
+			"fizz":  {Type: StringOrArrayOfString{"number"}},
+			"other": {Type: StringOrArrayOfString{"string"}},
+			"nested": {
+				Properties: map[string]*Schema{
+					"foo": {Type: StringOrArrayOfString{"boolean"}},
+				},
+				PatternProperties: map[string]*Schema{
+					"^f": {Type: StringOrArrayOfString{"string", "null"}},
+				},
+			},
+		},
+		PatternProperties: map[string]*Schema{
+			"^f": {Type: StringOrArrayOfString{"integer"}},
+		},
+	}
+
+	assert.Equal(t, s.PatternPropertyConflicts(), []string{"foo", "nested.foo"})
+}
+
+func TestOneOfOverlaps(t *testing.T) {
+	data := `
+# @schema
+# oneOf:
+#   - type: object
+#     properties:
+#       size: {type: integer}
+#   - type: object
+#     properties:
+#       size: {type: string}
+# @schema
+storage: {}
+# @schema
+# oneOf:
+#   - type: object
+#     properties:
+#       kind: {const: s3}
+#       bucket: {type: string}
+#     required: [kind]
+#   - type: object
+#     properties:
+#       kind: {const: gcs}
+#     required: [kind]
+#   - type: string
+# @schema
+backend: {}
+`
+	s := generateTestSchema(t, data, &GenerateOptions{})
+
+	assert.Equal(t, s.OneOfOverlaps(), []OneOfOverlap{{Path: "storage", First: 0, Second: 1}})
+}
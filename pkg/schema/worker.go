@@ -11,7 +11,6 @@ import (
 
 	"github.com/rsafonseca/helm-schema/pkg/chart"
 	"github.com/rsafonseca/helm-schema/pkg/util"
-	"gopkg.in/yaml.v3"
 )
 
 type Result struct {
@@ -27,6 +26,7 @@ func Worker(
 	schemaId string, schemaTitle string,
 	valueFileNames []string,
 	skipAutoGenerationConfig *SkipAutoGenerationConfig,
+	generateOptions *GenerateOptions,
 	outFile string,
 	queue <-chan string,
 	results chan<- Result,
@@ -102,6 +102,9 @@ func Worker(
 		}
 
 		// Optional preprocessing
+		if generateOptions.ReplaceTemplates {
+			content = util.ReplaceTemplateExpressions(content)
+		}
 		if uncomment {
 			// Remove comments from valid yaml
 			content, err = util.RemoveCommentsFromYaml(bytes.NewReader(content))
@@ -123,17 +126,30 @@ func Worker(
 			}
 		}
 
-		var values yaml.Node
-		err = yaml.Unmarshal(content, &values)
+		valuesOpts := *generateOptions
+		valuesOpts.ValuesPath = valuesPath
+		// an annotation on the document takes precedence
+		if valuesOpts.Title == "" {
+			valuesOpts.Title = schemaTitle
+		}
+		if valuesOpts.BaseURI == "" {
+			valuesOpts.BaseURI = schemaId
+		}
+		valuesOpts.KeepFullComment = keepFullComment
+		valuesOpts.DontRemoveHelmDocsPrefix = dontRemoveHelmDocsPrefix
+		valuesOpts.SkipAutoGeneration = skipAutoGenerationConfig
+		valuesSchema, err := generateDocuments(valuesOpts, content)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			results <- result
 			continue
 		}
-
-		result.Schema = *YamlToSchema(valuesPath, &values, keepFullComment, dontRemoveHelmDocsPrefix, skipAutoGenerationConfig, nil, "")
-		result.Schema.Title = schemaTitle
-		result.Schema.Id = schemaId
+		if err := valuesOpts.completeSchema(valuesSchema); err != nil {
+			result.Errors = append(result.Errors, err)
+			results <- result
+			continue
+		}
+		result.Schema = *valuesSchema
 		results <- result
 	}
 }
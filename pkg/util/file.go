@@ -13,6 +13,17 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// MaxLineSize is the maximum length of a line read by NewLineScanner. The default buffer of bufio.Scanner only
+// allows 64KB, which is too small for e.g. comments containing embedded base64 data.
+const MaxLineSize = 16 * 1024 * 1024
+
+// NewLineScanner returns a scanner, which reads the lines of the reader up to MaxLineSize
+func NewLineScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MaxLineSize)
+	return scanner
+}
+
 // ReadFileAndFixNewline reads the content of a io.Reader and replaces \r\n with \n
 func ReadFileAndFixNewline(reader io.Reader) ([]byte, error) {
 	content, err := io.ReadAll(reader)
@@ -65,13 +76,14 @@ func PrefixFirstYamlDocument(line, file string) error {
 // RemoveCommentsFromYaml tries to remove comments if they contain valid yaml
 func RemoveCommentsFromYaml(reader io.Reader) ([]byte, error) {
 	result := make([]byte, 0)
-	scanner := bufio.NewScanner(reader)
+	scanner := NewLineScanner(reader)
 
 	helmDocsMatcher := regexp.MustCompile(`^\s*#\s*--`)
 	commentMatcher := regexp.MustCompile(`^(\s*#\s*)(.*$)`)
 	commentYamlMapMatcher := regexp.MustCompile(`^(\s*#\s*)([^:]+:)(.*$)`)
 	whitespaceMatcher := regexp.MustCompile(`\s`)
 	schemaMatcher := regexp.MustCompile(`^\s*#\s@schema\s*`)
+	shebangMatcher := regexp.MustCompile(`^#!`)
 
 	var line string
 	var inDocs, inSchema bool
@@ -81,6 +93,13 @@ func RemoveCommentsFromYaml(reader io.Reader) ([]byte, error) {
 	for scanner.Scan() {
 		line = scanner.Text()
 
+		// Shebang-like lines (e.g. #!something in generated files) are never commented yaml,
+		// uncommenting them would result in a yaml tag
+		if shebangMatcher.MatchString(line) {
+			appendAndNLStr(&result, line)
+			continue
+		}
+
 		// Skip uncommenting the first comment block in the file, e.g. for when using something like # yaml-language-server: $schema=<urlToTheSchema>
 		if !headerCommentsParsed {
 			if commentMatcher.Match([]byte(line)) && !schemaMatcher.Match([]byte(line)) && !helmDocsMatcher.Match([]byte(line)) {
@@ -156,17 +175,125 @@ func RemoveCommentsFromYaml(reader io.Reader) ([]byte, error) {
 			appendAndNLStr(&result, line)
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 	// check if the new block is still valid yaml
 	err := yaml.Unmarshal(result, &unknownYaml)
 	if err != nil {
-		// Invalid yaml found,
-		fmt.Println(err)
-		panic("Invalid yaml after uncommenting:\n" + string(result))
+		return nil, fmt.Errorf("invalid yaml after uncommenting: %w\n%s", err, result)
 	}
 
 	return result, nil
 }
 
+// TemplatePlaceholder replaces helm template expressions in ReplaceTemplateExpressions, it matches
+// the default placeholder pattern of the schema package
+const TemplatePlaceholder = "${HELM_TEMPLATE}"
+
+var (
+	templateExpressionMatcher = regexp.MustCompile(`\{\{.*?\}\}`)
+	templateOnlyLineMatcher   = regexp.MustCompile(`^\s*(\{\{.*?\}\}\s*)+$`)
+	commentLineMatcher        = regexp.MustCompile(`^\s*#`)
+)
+
+// ReplaceTemplateExpressions makes templated values parsable as yaml. Lines which only consist of
+// template actions (e.g. {{- if .Values.foo }}) are blanked, other template expressions are replaced by
+// TemplatePlaceholder. Comments are kept as they are.
+func ReplaceTemplateExpressions(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if commentLineMatcher.MatchString(line) {
+			continue
+		}
+		if templateOnlyLineMatcher.MatchString(line) {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = templateExpressionMatcher.ReplaceAllLiteralString(line, TemplatePlaceholder)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// blockScalarMatcher matches lines starting a block scalar (e.g. "key: |-" or "- >"), whose content may contain tabs
+var blockScalarMatcher = regexp.MustCompile(`(^|[:-])\s*[|>][1-9+-]*\s*(#.*)?$`)
+
+// CheckTabIndentation returns an error naming the first line, which is indented with a tab. yaml only allows
+// spaces for indentation, the error of the yaml parser doesn't make that obvious. The content of block scalars,
+// multi-line quoted scalars, flow collections and comments may contain tabs.
+func CheckTabIndentation(content []byte) error {
+	blockIndent := -1
+	var flow flowState
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		spaces := len(line) - len(strings.TrimLeft(line, " "))
+		if blockIndent >= 0 {
+			if trimmed == "" || spaces > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if flow.continued() {
+			flow.scan(line)
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.Contains(line[:len(line)-len(trimmed)], "\t") {
+			return fmt.Errorf("line %d is indented with a tab, yaml only allows spaces for indentation: %q", i+1, line)
+		}
+		if blockScalarMatcher.MatchString(line) {
+			blockIndent = spaces
+			continue
+		}
+		flow.scan(line)
+	}
+	return nil
+}
+
+// flowState tracks the quoted scalars and flow collections, which continue on the next line
+type flowState struct {
+	quote byte
+	depth int
+}
+
+// continued returns true if the next line continues a quoted scalar or flow collection
+func (f *flowState) continued() bool {
+	return f.quote != 0 || f.depth > 0
+}
+
+// scan updates the state with the quotes and brackets of the line
+func (f *flowState) scan(line string) {
+	// a token (and so a quoted scalar) starts at the beginning of the line or after an indicator
+	tokenStart := true
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case f.quote == '"' && c == '\\':
+			i++
+		case f.quote != 0 && c == f.quote:
+			if c == '\'' && i+1 < len(line) && line[i+1] == '\'' {
+				i++
+				continue
+			}
+			f.quote = 0
+		case f.quote != 0:
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return
+		case (c == '"' || c == '\'') && tokenStart:
+			f.quote = c
+		case c == '[' || c == '{':
+			f.depth++
+		case (c == ']' || c == '}') && f.depth > 0:
+			f.depth--
+		}
+		if f.quote == 0 {
+			tokenStart = strings.IndexByte(" \t:,-[{?", c) >= 0
+		}
+	}
+}
+
 // IsRelativeFile checks if the given string is a relative path to a file
 func IsRelativeFile(root, relPath string) (string, error) {
 	if !path.IsAbs(relPath) {
@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -35,3 +36,115 @@ func TestReadFileAndFixNewline(t *testing.T) {
 		}
 	}
 }
+
+func TestRemoveCommentsFromYamlShebang(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{
+			input:  "#!generated by foo\n# header\n\n# foo: bar\nbaz: 1\n",
+			output: "#!generated by foo\n# header\n\nfoo: bar\nbaz: 1\n",
+		},
+		{
+			input:  "---\n#!generated by foo\n# foo: bar\nbaz: 1\n",
+			output: "---\n#!generated by foo\nfoo: bar\nbaz: 1\n",
+		},
+	}
+	for _, test := range tests {
+		content, err := RemoveCommentsFromYaml(bytes.NewReader([]byte(test.input)))
+		if err != nil {
+			t.Errorf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if string(content) != test.output {
+			t.Errorf("Was expecting %q, but got %q", test.output, content)
+		}
+	}
+}
+
+func TestRemoveCommentsFromYamlLongLine(t *testing.T) {
+	blob := strings.Repeat("A", 100*1024)
+	input := "foo: 1\n# blob: " + blob + "\nbar: 2\n"
+	content, err := RemoveCommentsFromYaml(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if expected := "foo: 1\nblob: " + blob + "\nbar: 2\n"; string(content) != expected {
+		t.Errorf("Was expecting the long line to be kept, but got %d bytes", len(content))
+	}
+
+	input = "foo: 1\n# blob: " + strings.Repeat("A", MaxLineSize) + "\nbar: 2\n"
+	if _, err := RemoveCommentsFromYaml(bytes.NewReader([]byte(input))); err == nil {
+		t.Error("Was expecting an error for a line longer than MaxLineSize")
+	}
+}
+
+func TestRemoveCommentsFromYamlInvalid(t *testing.T) {
+	input := "foo: 1\n# - bar\n"
+	_, err := RemoveCommentsFromYaml(bytes.NewReader([]byte(input)))
+	if err == nil {
+		t.Fatal("Was expecting an error for invalid yaml after uncommenting")
+	}
+	if !strings.Contains(err.Error(), "foo: 1\n- bar") {
+		t.Errorf("Was expecting the uncommented content in the error, but got: %v", err)
+	}
+}
+
+func TestCheckTabIndentation(t *testing.T) {
+	tests := []struct {
+		input string
+		line  string
+	}{
+		{input: "foo:\n  bar: 1\n", line: ""},
+		{input: "foo:\n\tbar: 1\n", line: "line 2"},
+		{input: "foo:\n  bar: 1\n  \tbaz: 2\n", line: "line 3"},
+		// tabs in comments and the content of block scalars are fine
+		{input: "\t# comment\nfoo: 1\n", line: ""},
+		{input: "makefile: |\n  all:\n  \techo foo\nbar: 1\n", line: ""},
+		{input: "makefile: |\n  all:\n\tbar: 1\n", line: "line 3"},
+		// and so may multi-line quoted scalars and flow collections
+		{input: "foo: \"a\n\tb\"\n", line: ""},
+		{input: "foo: 'it''s\n\tb'\n", line: ""},
+		{input: "list: [\n\ta, b\n]\n", line: ""},
+		{input: "list: [a, b]\n\tfoo: 1\n", line: "line 2"},
+		{input: "foo: it's\n\tbar: 1\n", line: "line 2"},
+	}
+	for _, test := range tests {
+		err := CheckTabIndentation([]byte(test.input))
+		if test.line == "" {
+			if err != nil {
+				t.Errorf("Wasn't expecting an error for %q, but got this: %v", test.input, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.line) {
+			t.Errorf("Was expecting an error naming %s for %q, but got: %v", test.line, test.input, err)
+		}
+	}
+}
+
+func TestReplaceTemplateExpressions(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{
+			input:  "name: {{ .Release.Name }}-svc\n",
+			output: "name: ${HELM_TEMPLATE}-svc\n",
+		},
+		{
+			input:  "{{- if .Values.enabled }}\nfoo: \"{{ .Values.foo | quote }}\"\n{{- end }}\n",
+			output: "\nfoo: \"${HELM_TEMPLATE}\"\n\n",
+		},
+		{
+			input:  "# {{ kept }}\nfoo: bar\n",
+			output: "# {{ kept }}\nfoo: bar\n",
+		},
+	}
+	for _, test := range tests {
+		content := ReplaceTemplateExpressions([]byte(test.input))
+		if string(content) != test.output {
+			t.Errorf("Was expecting %q, but got %q", test.output, content)
+		}
+	}
+}